@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/fx"
+	"log/slog"
+	"os"
+	"refresh/internal/pkg/audit"
+	"refresh/internal/pkg/auth"
+	"refresh/internal/pkg/auth/repo"
+	"refresh/internal/pkg/auth/usecase"
+	"refresh/internal/pkg/config"
+	"refresh/internal/pkg/db"
+	"refresh/internal/pkg/denylist"
+	"refresh/internal/pkg/metrics"
+	"refresh/internal/pkg/tokenizer"
+	"refresh/internal/pkg/tracing"
+	"refresh/migrations"
+	"refresh/pkg/logger"
+)
+
+// The module graph shared by every command, so that the server and the
+// admin commands read the same config and use the same repository code.
+// fx only builds what a command asks for: decoding a token does not connect
+// to the database.
+var (
+	configModule = fx.Provide(
+		config.Load,
+		tracing.NewTracerProvider,
+	)
+
+	storageModule = fx.Provide(
+		db.NewPostgresConn,
+		db.NewPostgresPool,
+		migrations.New,
+	)
+
+	authModule = fx.Provide(
+		tokenizer.New,
+		metrics.New,
+
+		fx.Annotate(repo.New, fx.As(new(auth.Repository))),
+		fx.Annotate(denylist.New, fx.As(new(auth.Denylist))),
+		fx.Annotate(audit.New, fx.As(fx.Self()), fx.As(new(auth.Auditor))),
+		fx.Annotate(usecase.New, fx.As(new(auth.Usecase))),
+	)
+)
+
+// newCommandLogger logs to stderr, leaving stdout to the command output.
+func newCommandLogger(cfg logger.Config) (*slog.Logger, error) {
+	return logger.New(os.Stderr, cfg)
+}
+
+// runApp starts an application built from the shared modules and options,
+// typically fx.Populate targets, calls fn and stops the application.
+func runApp(ctx context.Context, fn func(ctx context.Context) error, options ...fx.Option) error {
+	app := fx.New(
+		configModule,
+		storageModule,
+		authModule,
+		fx.Provide(newCommandLogger),
+		fx.Options(options...),
+		fx.NopLogger,
+	)
+	if err := app.Err(); err != nil {
+		return err
+	}
+
+	if err := app.Start(ctx); err != nil {
+		return err
+	}
+
+	err := fn(ctx)
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), app.StopTimeout())
+	defer cancel()
+	return errors.Join(err, app.Stop(stopCtx))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/fx"
+	"os"
+	"path/filepath"
+	"refresh/internal/pkg/config"
+	"refresh/internal/pkg/secrets"
+	"refresh/internal/pkg/tokenizer"
+	"text/tabwriter"
+)
+
+const (
+	keyEnv         = "JWT_SECRET"
+	previousKeyEnv = "JWT_PREVIOUS_SECRET"
+)
+
+var keysCommand = &command{
+	name:    "keys",
+	summary: "generate, rotate or list JWT signing keys",
+	subcommands: []*command{
+		{name: "generate", summary: "print a new random signing key", run: keysGenerate},
+		{name: "rotate", summary: "replace the signing key in JWT_SECRET_FILE", run: keysRotate},
+		{name: "list", summary: "show the configured signing keys", run: keysList},
+	},
+}
+
+func keysGenerate(_ context.Context, args []string) error {
+	if err := parseFlags(newFlagSet("keys generate", ""), args, 0); err != nil {
+		return err
+	}
+
+	key, err := tokenizer.GenerateKey()
+	if err != nil {
+		return err
+	}
+	fmt.Println(key)
+	return nil
+}
+
+// keysRotate writes a new key to JWT_SECRET_FILE. The replaced key moves to
+// JWT_PREVIOUS_SECRET_FILE, if set, so tokens it signed stay valid until
+// they expire. Running servers pick the keys up on SIGHUP.
+func keysRotate(ctx context.Context, args []string) error {
+	if err := parseFlags(newFlagSet("keys rotate", ""), args, 0); err != nil {
+		return err
+	}
+
+	keyPath := os.Getenv(keyEnv + secrets.FileSuffix)
+	if keyPath == "" {
+		return fmt.Errorf("%s%s is not set; store the output of keys generate wherever %s comes from instead",
+			keyEnv, secrets.FileSuffix, keyEnv)
+	}
+	previousPath := os.Getenv(previousKeyEnv + secrets.FileSuffix)
+
+	var cfg *config.Config
+	return runApp(ctx, func(context.Context) error {
+		key, err := tokenizer.GenerateKey()
+		if err != nil {
+			return err
+		}
+
+		if previousPath != "" {
+			if err = writeSecretFile(previousPath, cfg.Token.KeyJWT); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "warning: %s%s is not set, tokens signed with key %s stop validating after a restart\n",
+				previousKeyEnv, secrets.FileSuffix, tokenizer.KeyID(cfg.Token.KeyJWT))
+		}
+		if err = writeSecretFile(keyPath, []byte(key)); err != nil {
+			return err
+		}
+
+		fmt.Printf("signing key %s replaced by %s; send SIGHUP to running servers to apply\n",
+			tokenizer.KeyID(cfg.Token.KeyJWT), tokenizer.KeyID([]byte(key)))
+		return nil
+	}, fx.Populate(&cfg))
+}
+
+func keysList(ctx context.Context, args []string) error {
+	if err := parseFlags(newFlagSet("keys list", ""), args, 0); err != nil {
+		return err
+	}
+
+	var cfg *config.Config
+	return runApp(ctx, func(context.Context) error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tID\tSOURCE")
+		fmt.Fprintf(w, "current\t%s\t%s\n", tokenizer.KeyID(cfg.Token.KeyJWT), keySource(cfg, keyEnv))
+		if len(cfg.Token.PreviousKeyJWT) > 0 {
+			fmt.Fprintf(w, "previous\t%s\t%s\n",
+				tokenizer.KeyID(cfg.Token.PreviousKeyJWT), keySource(cfg, previousKeyEnv))
+		}
+		return w.Flush()
+	}, fx.Populate(&cfg))
+}
+
+// keySource tells where the secret name was read from, following the order
+// of secrets.Resolver.
+func keySource(cfg *config.Config, name string) string {
+	if path := os.Getenv(name + secrets.FileSuffix); path != "" {
+		return "file " + path
+	}
+	if os.Getenv(name) != "" {
+		return "env " + name
+	}
+	return cfg.Secrets.Provider
+}
+
+// writeSecretFile replaces the file at path atomically, so that a server
+// reloading concurrently never reads a partial key.
+func writeSecretFile(path string, secret []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = fmt.Fprintf(tmp, "%s\n", secret)
+	if err = errors.Join(err, tmp.Chmod(0o600), tmp.Close()); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
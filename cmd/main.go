@@ -2,61 +2,119 @@ package main
 
 import (
 	"context"
-	"go.uber.org/fx"
-	"go.uber.org/fx/fxevent"
-	"log/slog"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
-	"refresh/internal/pkg/auth"
-	handlerAuth "refresh/internal/pkg/auth/delivery/http"
-	"refresh/internal/pkg/auth/repo"
-	"refresh/internal/pkg/auth/usecase"
-	"refresh/internal/pkg/config"
-	"refresh/internal/pkg/db"
-	"refresh/internal/pkg/server"
-	"refresh/internal/pkg/tokenizer"
-	"refresh/migrations"
-	"refresh/pkg/logger"
+	"path/filepath"
+	"strings"
 	"syscall"
 )
 
-func main() {
-	app := fx.New(
-		fx.Provide(
-			logger.SetupLogger,
-			server.NewRouter,
+// errUsage is returned after usage has been printed for invalid arguments.
+var errUsage = errors.New("invalid usage")
 
-			config.MustLoad,
+// command is a CLI command. It either runs itself or dispatches to one of
+// its subcommands.
+type command struct {
+	name        string
+	summary     string
+	run         func(ctx context.Context, args []string) error
+	subcommands []*command
+}
 
-			db.NewPostgresConn,
-			db.NewPostgresPool,
+var programName = filepath.Base(os.Args[0])
 
-			tokenizer.New,
+var root = &command{
+	name: programName,
+	subcommands: []*command{
+		serveCommand,
+		migrateCommand,
+		keysCommand,
+		sessionsCommand,
+		tokenCommand,
+	},
+}
 
-			fx.Annotate(repo.New, fx.As(new(auth.Repository))),
-			fx.Annotate(usecase.New, fx.As(new(auth.Usecase))),
-			handlerAuth.New,
-		),
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		fx.WithLogger(func(logger *slog.Logger) fxevent.Logger {
-			return &fxevent.SlogLogger{Logger: logger}
-		}),
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{serveCommand.name}
+	}
 
-		fx.Invoke(
-			server.RunServer,
-			migrations.RunMigrations,
-		),
-	)
+	err := root.execute(ctx, "", args)
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
 
-	ctx := context.Background()
+func (c *command) execute(ctx context.Context, prefix string, args []string) error {
+	path := strings.TrimSpace(prefix + " " + c.name)
+	if c.run != nil {
+		return c.run(ctx, args)
+	}
 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	if len(args) == 0 {
+		c.usage(os.Stderr, path)
+		return errUsage
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		c.usage(os.Stdout, path)
+		return nil
+	}
 
-	if err := app.Start(ctx); err != nil {
-		panic(err)
+	for _, sub := range c.subcommands {
+		if sub.name == args[0] {
+			return sub.execute(ctx, path, args[1:])
+		}
 	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	c.usage(os.Stderr, path)
+	return errUsage
+}
 
-	<-stop
-	app.Stop(ctx)
+func (c *command) usage(w io.Writer, path string) {
+	fmt.Fprintf(w, "usage: %s <command> [arguments]\n\ncommands:\n", path)
+	for _, sub := range c.subcommands {
+		fmt.Fprintf(w, "  %-10s %s\n", sub.name, sub.summary)
+	}
+}
+
+// newFlagSet returns a flag set for the command at path, which reports
+// parse errors instead of exiting.
+func newFlagSet(path, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s %s\n", programName, path, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args and checks that exactly nargs positional
+// arguments remain.
+func parseFlags(fs *flag.FlagSet, args []string, nargs int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_Execute(t *testing.T) {
+	var got []string
+	cmd := &command{
+		name: "test",
+		subcommands: []*command{
+			{name: "group", subcommands: []*command{
+				{name: "leaf", run: func(_ context.Context, args []string) error {
+					got = args
+					return nil
+				}},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		expected    []string
+		expectedErr error
+	}{
+		{name: "Leaf", args: []string{"group", "leaf", "-flag", "x"}, expected: []string{"-flag", "x"}},
+		{name: "Help", args: []string{"group", "help"}},
+		{name: "Missing subcommand", args: []string{"group"}, expectedErr: errUsage},
+		{name: "Unknown subcommand", args: []string{"group", "other"}, expectedErr: errUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			err := cmd.execute(context.Background(), "", tt.args)
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParseForceVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expected    int
+		expectedErr error
+	}{
+		{name: "Version", args: []string{"3"}, expected: 3},
+		{name: "No migration applied", args: []string{"-1"}, expected: -1},
+		{name: "After separator", args: []string{"--", "-1"}, expected: -1},
+		{name: "Not a number", args: []string{"latest"}, expectedErr: errUsage},
+		{name: "Unknown flag", args: []string{"-x"}, expectedErr: errUsage},
+		{name: "Missing version", expectedErr: errUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFlagSet("migrate force", "<version>")
+			fs.SetOutput(io.Discard)
+
+			version, err := parseForceVersion(fs, tt.args)
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expected, version)
+		})
+	}
+}
+
+func TestWriteSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwt_secret")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+
+	require.NoError(t, writeSecretFile(path, []byte("new")))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
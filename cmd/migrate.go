@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go.uber.org/fx"
+	"refresh/migrations"
+	"strconv"
+)
+
+var migrateCommand = &command{
+	name:    "migrate",
+	summary: "apply, roll back or inspect database migrations",
+	subcommands: []*command{
+		{name: "up", summary: "apply all pending migrations", run: migrateUp},
+		{name: "down", summary: "roll back migrations", run: migrateDown},
+		{name: "status", summary: "show the schema version", run: migrateStatus},
+		{name: "goto", summary: "migrate up or down to a version", run: migrateGoto},
+		{name: "force", summary: "set the version after repairing a dirty schema", run: migrateForce},
+	},
+}
+
+// withMigrator runs fn with a Migrator and closes it afterwards.
+func withMigrator(ctx context.Context, fn func(ctx context.Context, m *migrations.Migrator) error) error {
+	var m *migrations.Migrator
+	return runApp(ctx, func(ctx context.Context) error {
+		defer m.Close()
+		return fn(ctx, m)
+	}, fx.Populate(&m))
+}
+
+func migrateUp(ctx context.Context, args []string) error {
+	if err := parseFlags(newFlagSet("migrate up", ""), args, 0); err != nil {
+		return err
+	}
+	return withMigrator(ctx, func(ctx context.Context, m *migrations.Migrator) error {
+		if err := m.Up(ctx); err != nil {
+			return err
+		}
+		return printStatus(m)
+	})
+}
+
+func migrateDown(ctx context.Context, args []string) error {
+	fs := newFlagSet("migrate down", "[-steps n | -all]")
+	steps := fs.Int("steps", 1, "number of migrations to roll back")
+	all := fs.Bool("all", false, "roll back every migration")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	if *all {
+		*steps = 0
+	} else if *steps < 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	return withMigrator(ctx, func(ctx context.Context, m *migrations.Migrator) error {
+		if err := m.Down(ctx, *steps); err != nil {
+			return err
+		}
+		return printStatus(m)
+	})
+}
+
+func migrateStatus(ctx context.Context, args []string) error {
+	if err := parseFlags(newFlagSet("migrate status", ""), args, 0); err != nil {
+		return err
+	}
+	return withMigrator(ctx, func(_ context.Context, m *migrations.Migrator) error {
+		return printStatus(m)
+	})
+}
+
+func migrateGoto(ctx context.Context, args []string) error {
+	fs := newFlagSet("migrate goto", "<version>")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	version, err := strconv.ParseUint(fs.Arg(0), 10, 64)
+	if err != nil || version == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	return withMigrator(ctx, func(ctx context.Context, m *migrations.Migrator) error {
+		if err := m.Goto(ctx, uint(version)); err != nil {
+			return err
+		}
+		return printStatus(m)
+	})
+}
+
+func migrateForce(ctx context.Context, args []string) error {
+	version, err := parseForceVersion(newFlagSet("migrate force", "<version>"), args)
+	if err != nil {
+		return err
+	}
+
+	return withMigrator(ctx, func(ctx context.Context, m *migrations.Migrator) error {
+		if err := m.Force(ctx, version); err != nil {
+			return err
+		}
+		return printStatus(m)
+	})
+}
+
+// parseForceVersion reads the version of migrate force. The flag package
+// takes -1 for an unknown flag, so a lone numeric argument is used as is;
+// "migrate force -- -1" works too.
+func parseForceVersion(fs *flag.FlagSet, args []string) (int, error) {
+	if len(args) == 1 {
+		if version, err := strconv.Atoi(args[0]); err == nil {
+			return version, nil
+		}
+	}
+
+	if err := parseFlags(fs, args, 1); err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		fs.Usage()
+		return 0, errUsage
+	}
+	return version, nil
+}
+
+func printStatus(m *migrations.Migrator) error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	state := "up to date"
+	switch {
+	case status.Dirty:
+		state = "dirty, repair the schema and run migrate force"
+	case status.Version < status.Latest:
+		state = fmt.Sprintf("%d pending", status.Latest-status.Version)
+	case status.Version > status.Latest:
+		state = "ahead of this binary"
+	}
+	fmt.Printf("version %d of %d (%s)\n", status.Version, status.Latest, state)
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxevent"
+	"log/slog"
+	handlerAudit "refresh/internal/pkg/audit/delivery/http"
+	handlerAuthGrpc "refresh/internal/pkg/auth/delivery/grpc"
+	handlerAuth "refresh/internal/pkg/auth/delivery/http"
+	"refresh/internal/pkg/config"
+	"refresh/internal/pkg/cors"
+	"refresh/internal/pkg/csrf"
+	"refresh/internal/pkg/db"
+	"refresh/internal/pkg/health"
+	"refresh/internal/pkg/server"
+	"refresh/internal/pkg/tokenizer"
+	"refresh/migrations"
+	"refresh/pkg/logger"
+	"time"
+)
+
+var serveCommand = &command{
+	name:    "serve",
+	summary: "run the HTTP and gRPC servers (default)",
+	run:     serve,
+}
+
+var serverModule = fx.Options(
+	fx.Provide(
+		logger.NewFromConfig,
+		server.NewRouter,
+		server.NewReadiness,
+		server.NewAdminMiddleware,
+		cors.New,
+		csrf.New,
+
+		config.NewWatcher,
+
+		handlerAuth.New,
+		handlerAuthGrpc.New,
+		handlerAuthGrpc.NewServer,
+		handlerAudit.New,
+
+		health.New,
+		health.AsChecker(db.NewHealthChecker),
+		health.AsChecker(migrations.NewHealthChecker),
+		health.AsChecker(tokenizer.NewHealthChecker),
+		health.AsChecker(server.NewHealthChecker),
+	),
+
+	// Upper bound for all OnStop hooks; the HTTP server drains within
+	// its own shutdownDelay and drainTimeout.
+	fx.StopTimeout(time.Minute),
+
+	fx.WithLogger(func(logger *slog.Logger) fxevent.Logger {
+		return &fxevent.SlogLogger{Logger: logger}
+	}),
+
+	// Migrations run first, while the graph is built, so that no server
+	// starts against an outdated schema.
+	fx.Invoke(
+		migrations.RunMigrations,
+		server.RunServer,
+		handlerAuthGrpc.RunServer,
+		func(*config.Watcher) {},
+	),
+)
+
+func serve(ctx context.Context, args []string) error {
+	if err := parseFlags(newFlagSet("serve", ""), args, 0); err != nil {
+		return err
+	}
+
+	app := fx.New(
+		configModule,
+		storageModule,
+		authModule,
+		serverModule,
+	)
+
+	if err := app.Start(ctx); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-app.Wait():
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), app.StopTimeout())
+	defer cancel()
+
+	return app.Stop(stopCtx)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/fx"
+	"os"
+	"refresh/internal/pkg/audit"
+	"refresh/internal/pkg/auth"
+	"text/tabwriter"
+	"time"
+)
+
+var sessionsCommand = &command{
+	name:    "sessions",
+	summary: "list or revoke refresh sessions",
+	subcommands: []*command{
+		{name: "list", summary: "list the sessions of a user", run: sessionsList},
+		{name: "revoke", summary: "revoke all sessions of a user", run: sessionsRevoke},
+	},
+}
+
+// userFlag registers the required -user flag.
+func userFlag(fs *flag.FlagSet) *string {
+	return fs.String("user", "", "user ID (required)")
+}
+
+func parseUser(fs *flag.FlagSet, user string) (uuid.UUID, error) {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		fmt.Fprintf(fs.Output(), "invalid -user %q\n", user)
+		fs.Usage()
+		return uuid.Nil, errUsage
+	}
+	return userID, nil
+}
+
+func sessionsList(ctx context.Context, args []string) error {
+	fs := newFlagSet("sessions list", "-user <id>")
+	user := userFlag(fs)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	userID, err := parseUser(fs, *user)
+	if err != nil {
+		return err
+	}
+
+	var uc auth.Usecase
+	return runApp(ctx, func(ctx context.Context) error {
+		sessions, err := uc.ListSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SESSION\tUSER\tIP\tCREATED")
+		for _, s := range sessions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.SessionID, s.UserID, s.UserIP, s.CreatedAt.Format(time.RFC3339))
+		}
+		return w.Flush()
+	}, fx.Populate(&uc))
+}
+
+// sessionsRevoke ends the sessions of a user. Every token issued in them is
+// denied from now on.
+func sessionsRevoke(ctx context.Context, args []string) error {
+	fs := newFlagSet("sessions revoke", "-user <id>")
+	user := userFlag(fs)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	userID, err := parseUser(fs, *user)
+	if err != nil {
+		return err
+	}
+
+	var uc auth.Usecase
+	return runApp(ctx, func(ctx context.Context) error {
+		ctx = audit.WithClient(ctx, "", programName+" sessions revoke")
+		if err := uc.RevokeSessions(ctx, userID); err != nil {
+			return err
+		}
+
+		fmt.Printf("revoked the sessions of user %s\n", userID)
+		return nil
+	}, fx.Populate(&uc))
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	AuditEventLogin       = "login"
+	AuditEventRefresh     = "refresh"
+	AuditEventRevoke      = "revoke"
+	AuditEventIPChanged   = "ip_changed"
+	AuditEventTokenIssued = "token_issued"
+
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent is a security-relevant action. UserID and SessionID are uuid.Nil
+// when the token could not be attributed to a user.
+type AuditEvent struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	UserID    uuid.UUID `json:"user_id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	SessionID uuid.UUID `json:"session_id"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditFilter struct {
+	UserID uuid.UUID
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
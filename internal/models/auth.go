@@ -5,20 +5,65 @@ import (
 	"time"
 )
 
+const (
+	TokenTypeHintAccess  = "access_token"
+	TokenTypeHintRefresh = "refresh_token"
+)
+
+const (
+	TokenTypeBearer = "Bearer"
+	TokenTypeDPoP   = "DPoP"
+)
+
+// Values of the typ claim, which keeps refresh tokens from being accepted
+// as access tokens and the other way round.
+const (
+	TokenUseAccess  = "access"
+	TokenUseRefresh = "refresh"
+)
+
 type PairToken struct {
 	AccessToken     string    `json:"access_token"`
-	RefreshToken    string    `json:"refresh_token"`
+	RefreshToken    string    `json:"refresh_token,omitempty"`
+	TokenType       string    `json:"token_type,omitempty"`
 	ExpAccessToken  time.Time `json:"-"`
 	ExpRefreshToken time.Time `json:"-"`
+	// SessionID is the sid shared by every token of the session.
+	SessionID uuid.UUID `json:"-"`
 }
 
 type TokenPayload struct {
+	ID uuid.UUID `json:"jti"`
+	// SessionID ties the tokens issued by one login and its refreshes
+	// together, so that revoking the session denies all of them. uuid.Nil
+	// for tokens issued before sessions had identifiers.
+	SessionID uuid.UUID `json:"sid"`
+	// Use is TokenUseAccess or TokenUseRefresh, empty for tokens issued
+	// before the typ claim.
+	Use    string    `json:"typ"`
 	UserID uuid.UUID `json:"user_id"`
 	UserIP string    `json:"user_ip"`
 	Exp    time.Time `json:"exp"`
+	Scopes []string  `json:"scope"`
+	Roles  []string  `json:"roles"`
+	// CertThumbprint binds the token to a client certificate (RFC 8705
+	// cnf x5t#S256). Empty for unbound tokens.
+	CertThumbprint string `json:"cnf_x5t_s256,omitempty"`
+	// KeyThumbprint binds the token to a DPoP key (RFC 9449 cnf jkt).
+	// Empty for unbound tokens.
+	KeyThumbprint string `json:"cnf_jkt,omitempty"`
 }
 
 type Session struct {
+	SessionID uuid.UUID `json:"session_id"`
 	UserID    uuid.UUID `json:"user_id"`
 	HashToken string    `json:"hash_token"`
+	UserIP    string    `json:"user_ip"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Permissions are the roles assigned to a user and the scopes those roles grant.
+type Permissions struct {
+	Roles  []string `json:"roles"`
+	Scopes []string `json:"scopes"`
 }
@@ -0,0 +1,106 @@
+// Package accesslog correlates HTTP requests with X-Request-ID and writes one
+// structured log line per request.
+package accesslog
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"refresh/pkg/logger"
+	"refresh/pkg/responser"
+	"time"
+)
+
+const maxRequestIDLength = 128
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+type routeKey struct{}
+
+// Middleware takes the request ID from X-Request-ID, or generates one, and
+// echoes it in the response. Handlers get a logger carrying the request ID
+// through logger.FromContext, and attributes they add with logger.AddAttrs
+// end up in the access log line. It wraps the router itself, so that
+// requests no route matches are logged too; Route records the matched
+// route.
+func Middleware(log *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(responser.RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = uuid.NewString()
+			}
+			w.Header().Set(responser.RequestIDHeader, requestID)
+
+			reqLog := log.With("request_id", requestID)
+			route := r.URL.Path
+			ctx := logger.NewContext(r.Context(), reqLog)
+			ctx = context.WithValue(ctx, routeKey{}, &route)
+			ctx, extra := logger.WithAttrs(ctx)
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			attrs := []any{
+				"method", r.Method,
+				"route", route,
+				"status", rec.status,
+				"bytes", rec.bytes,
+				"duration", time.Since(start),
+				"remote_addr", r.RemoteAddr,
+				"user_agent", r.UserAgent(),
+			}
+			attrs = append(attrs, extra()...)
+			reqLog.Info("http request", attrs...)
+		})
+	}
+}
+
+// Route records the path template of the matched route for Middleware,
+// which otherwise logs the request path. It is installed with
+// mux.Router.Use.
+func Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeKey{}).(*string); ok {
+			if current := mux.CurrentRoute(r); current != nil {
+				if tpl, err := current.GetPathTemplate(); err == nil {
+					*route = tpl
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID accepts client-supplied IDs that are short and printable so
+// they cannot forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
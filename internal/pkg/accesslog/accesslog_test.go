@@ -0,0 +1,111 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"refresh/pkg/logger"
+	"refresh/pkg/responser"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name          string
+		requestID     string
+		wantGenerated bool
+	}{
+		{name: "propagates request id", requestID: "abc-123"},
+		{name: "generates missing request id", wantGenerated: true},
+		{name: "replaces invalid request id", requestID: "bad\nid", wantGenerated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+			router := mux.NewRouter()
+			router.Use(Route)
+			router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				logger.FromContext(r.Context(), nil).Info("handler")
+				logger.AddAttrs(r.Context(), "user_id", userID)
+				responser.Send401(w, "nope")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+			if tt.requestID != "" {
+				req.Header.Set(responser.RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			Middleware(log)(router).ServeHTTP(w, req)
+
+			requestID := w.Header().Get(responser.RequestIDHeader)
+			if tt.wantGenerated {
+				_, err := uuid.Parse(requestID)
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.requestID, requestID)
+			}
+
+			var problem responser.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, requestID, problem.RequestID)
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 2)
+
+			var handlerLine, accessLine map[string]any
+			require.NoError(t, json.Unmarshal([]byte(lines[0]), &handlerLine))
+			require.NoError(t, json.Unmarshal([]byte(lines[1]), &accessLine))
+
+			assert.Equal(t, requestID, handlerLine["request_id"])
+			assert.Equal(t, requestID, accessLine["request_id"])
+			assert.Equal(t, "/users/{id}", accessLine["route"])
+			assert.Equal(t, float64(http.StatusUnauthorized), accessLine["status"])
+			assert.Equal(t, userID.String(), accessLine["user_id"])
+		})
+	}
+}
+
+func TestMiddleware_UnmatchedRoute(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Route)
+	router.HandleFunc("/users/{id}", func(http.ResponseWriter, *http.Request) {}).Methods(http.MethodGet)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{name: "not found", method: http.MethodGet, path: "/missing", expectedCode: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPost, path: "/users/42", expectedCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&buf, nil))
+			w := httptest.NewRecorder()
+
+			Middleware(log)(router).ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NotEmpty(t, w.Header().Get(responser.RequestIDHeader))
+			var accessLine map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &accessLine))
+			assert.Equal(t, tt.path, accessLine["route"])
+			assert.Equal(t, float64(tt.expectedCode), accessLine["status"])
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/fx"
+	"log/slog"
+	"refresh/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	insertEvent  = `INSERT INTO audit_events (type, user_id, ip, user_agent, session_id, outcome, reason, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	selectEvents = `SELECT id, type, user_id, ip, user_agent, session_id, outcome, reason, created_at FROM audit_events`
+
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+type Params struct {
+	fx.In
+
+	Config    Config
+	DB        *pgxpool.Pool
+	Logger    *slog.Logger
+	Lifecycle fx.Lifecycle
+}
+
+// Auditor records security-relevant events. Events are written to Postgres
+// and, when configured, to a JSON-lines file. A failed write is logged but
+// never fails the operation being audited.
+type Auditor struct {
+	db   *pgxpool.Pool
+	sink *fileSink
+	log  *slog.Logger
+	now  func() time.Time
+}
+
+func New(p Params) (*Auditor, error) {
+	a := &Auditor{db: p.DB, log: p.Logger, now: time.Now}
+
+	if p.Config.FilePath != "" {
+		sink, err := newFileSink(p.Config.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		a.sink = sink
+		p.Lifecycle.Append(fx.Hook{
+			OnStop: func(context.Context) error {
+				return sink.close()
+			},
+		})
+	}
+
+	return a, nil
+}
+
+func (a *Auditor) Record(ctx context.Context, event *models.AuditEvent) {
+	c := clientFromContext(ctx)
+	if event.IP == "" {
+		event.IP = c.ip
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = c.userAgent
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = a.now()
+	}
+
+	err := a.db.QueryRow(ctx, insertEvent,
+		event.Type,
+		nullUUID(event.UserID),
+		event.IP,
+		event.UserAgent,
+		nullUUID(event.SessionID),
+		event.Outcome,
+		event.Reason,
+		event.CreatedAt,
+	).Scan(&event.ID)
+	if err != nil {
+		a.log.Error("failed to store audit event", "error", err, "type", event.Type, "user_id", event.UserID)
+	}
+
+	if a.sink != nil {
+		if err = a.sink.write(event); err != nil {
+			a.log.Error("failed to write audit event", "error", err, "type", event.Type, "user_id", event.UserID)
+		}
+	}
+}
+
+// Query returns events matching the filter, newest first.
+func (a *Auditor) Query(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.Replace(condition, "?", "$"+strconv.Itoa(len(args)), 1))
+	}
+
+	if filter.UserID != uuid.Nil {
+		addCondition("user_id = ?", filter.UserID)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < ?", filter.To)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	query := selectEvents
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit)
+	query += " ORDER BY created_at DESC, id DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := a.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.AuditEvent, error) {
+		var (
+			event     models.AuditEvent
+			userID    *uuid.UUID
+			sessionID *uuid.UUID
+		)
+		err := row.Scan(&event.ID, &event.Type, &userID, &event.IP, &event.UserAgent, &sessionID, &event.Outcome, &event.Reason, &event.CreatedAt)
+		if userID != nil {
+			event.UserID = *userID
+		}
+		if sessionID != nil {
+			event.SessionID = *sessionID
+		}
+		return event, err
+	})
+}
+
+func nullUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
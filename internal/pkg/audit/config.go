@@ -0,0 +1,7 @@
+package audit
+
+type Config struct {
+	// FilePath is an optional JSON-lines file every event is appended to in
+	// addition to the audit_events table.
+	FilePath string `yaml:"filePath" env:"AUDIT_FILE_PATH"`
+}
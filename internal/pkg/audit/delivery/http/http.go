@@ -0,0 +1,102 @@
+package http
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/fx"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"refresh/internal/models"
+	"refresh/internal/pkg/audit"
+	"refresh/pkg/logger"
+	"refresh/pkg/responser"
+	"strconv"
+	"time"
+)
+
+// ReadScope is required to query the audit log.
+const ReadScope = "audit:read"
+
+const (
+	userIDParam = "user_id"
+	fromParam   = "from"
+	toParam     = "to"
+	limitParam  = "limit"
+)
+
+type Params struct {
+	fx.In
+
+	Auditor *audit.Auditor
+	Logger  *slog.Logger
+}
+
+type Handler struct {
+	a   *audit.Auditor
+	log *slog.Logger
+}
+
+func New(p Params) *Handler {
+	return &Handler{a: p.Auditor, log: p.Logger}
+}
+
+// Query lists audit events, newest first. from and to are RFC 3339
+// timestamps bounding created_at as [from, to).
+func (h *Handler) Query(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		h.logger(r).Error("invalid audit filter", "error", err)
+		responser.Send400(w, err.Error())
+		return
+	}
+
+	events, err := h.a.Query(r.Context(), filter)
+	if err != nil {
+		h.logger(r).Error("query audit events", "error", err)
+		responser.Send500(w)
+		return
+	}
+
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+	responser.Send200(w, events)
+}
+
+func parseFilter(query url.Values) (models.AuditFilter, error) {
+	var (
+		filter models.AuditFilter
+		err    error
+	)
+
+	if raw := query.Get(userIDParam); raw != "" {
+		if filter.UserID, err = uuid.Parse(raw); err != nil {
+			return filter, errors.New("invalid user_id")
+		}
+	}
+	if raw := query.Get(fromParam); raw != "" {
+		if filter.From, err = time.Parse(time.RFC3339, raw); err != nil {
+			return filter, errors.New("invalid from")
+		}
+	}
+	if raw := query.Get(toParam); raw != "" {
+		if filter.To, err = time.Parse(time.RFC3339, raw); err != nil {
+			return filter, errors.New("invalid to")
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, errors.New("from must be before to")
+	}
+	if raw := query.Get(limitParam); raw != "" {
+		if filter.Limit, err = strconv.Atoi(raw); err != nil || filter.Limit <= 0 || filter.Limit > audit.MaxLimit {
+			return filter, errors.New("invalid limit")
+		}
+	}
+
+	return filter, nil
+}
+
+func (h *Handler) logger(r *http.Request) *slog.Logger {
+	return logger.FromContext(r.Context(), h.log)
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"refresh/internal/models"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	userID := uuid.New()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		want    models.AuditFilter
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			query: url.Values{},
+			want:  models.AuditFilter{},
+		},
+		{
+			name: "all params",
+			query: url.Values{
+				"user_id": {userID.String()},
+				"from":    {from.Format(time.RFC3339)},
+				"to":      {to.Format(time.RFC3339)},
+				"limit":   {"50"},
+			},
+			want: models.AuditFilter{UserID: userID, From: from, To: to, Limit: 50},
+		},
+		{
+			name:    "invalid user id",
+			query:   url.Values{"user_id": {"nope"}},
+			wantErr: true,
+		},
+		{
+			name:    "inverted range",
+			query:   url.Values{"from": {to.Format(time.RFC3339)}, "to": {from.Format(time.RFC3339)}},
+			wantErr: true,
+		},
+		{
+			name:    "limit above max",
+			query:   url.Values{"limit": {"5000"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFilter(tt.query)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.From.Equal(got.From))
+			assert.True(t, tt.want.To.Equal(got.To))
+			assert.Equal(t, tt.want.UserID, got.UserID)
+			assert.Equal(t, tt.want.Limit, got.Limit)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"refresh/internal/models"
+	"sync"
+)
+
+// fileSink appends events to a JSON-lines file.
+type fileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+	return &fileSink{file: file}, nil
+}
+
+func (s *fileSink) write(event *models.AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.file.Write(line)
+	return err
+}
+
+func (s *fileSink) close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authv1 "refresh/api/auth/v1"
+	"refresh/internal/models"
+	"refresh/internal/pkg/mtls"
+	"slices"
+	"strings"
+)
+
+const (
+	// SessionsReadScope is required to list sessions with an access token.
+	// It covers the sessions of the token's own user only.
+	SessionsReadScope = "sessions:read"
+	// SessionsAdminScope additionally lets an access token list the
+	// sessions of any user.
+	SessionsAdminScope = "sessions:admin"
+)
+
+// protectedMethods maps the methods that need an authenticated caller to
+// the scope its access token must carry.
+var protectedMethods = map[string]string{
+	authv1.AuthService_ListSessions_FullMethodName: SessionsReadScope,
+}
+
+// authInterceptor authenticates calls to protectedMethods with an access
+// token sent as "authorization: Bearer <token>" metadata or, without a
+// token, with a verified client certificate. It runs after
+// mtls.UnaryServerInterceptor, so Validate sees the peer certificate of
+// certificate-bound tokens; DPoP-bound tokens are refused, as gRPC calls
+// carry no proof.
+func (h *Handler) authInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	scope, ok := protectedMethods[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	token, ok := bearerToken(ctx)
+	if !ok {
+		if _, ok := mtls.FromContext(ctx); ok {
+			return handler(ctx, req)
+		}
+		h.log.Error("access token in metadata not found", "method", info.FullMethod)
+		return nil, status.Error(codes.Unauthenticated, "access token not found")
+	}
+
+	payload, err := h.uc.Validate(ctx, token)
+	if err != nil {
+		h.log.Error("authenticate call", "method", info.FullMethod, "error", err)
+		return nil, toStatus(err)
+	}
+
+	if !slices.Contains(payload.Scopes, scope) {
+		h.log.Error("insufficient scope", "method", info.FullMethod, "scope", scope)
+		return nil, status.Error(codes.PermissionDenied, "insufficient scope")
+	}
+
+	return handler(context.WithValue(ctx, callerKey{}, payload), req)
+}
+
+type callerKey struct{}
+
+// mayReadSessions reports whether the caller may list the sessions of
+// userID: its own with an access token, any with SessionsAdminScope or a
+// verified client certificate.
+func mayReadSessions(ctx context.Context, userID uuid.UUID) bool {
+	payload, ok := ctx.Value(callerKey{}).(*models.TokenPayload)
+	if !ok {
+		// authInterceptor let the call through on a client certificate.
+		return true
+	}
+	return payload.UserID == userID || slices.Contains(payload.Scopes, SessionsAdminScope)
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", false
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,22 @@
+package grpc
+
+import (
+	"errors"
+	"refresh/internal/pkg/mtls"
+	"time"
+)
+
+type Config struct {
+	Address         string        `yaml:"address" env-default:"localhost:9090"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env-default:"10s"`
+	TLS             mtls.Config   `yaml:"tls"`
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	if c.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("shutdownTimeout must not be negative"))
+	}
+	errs = append(errs, c.TLS.Validate())
+	return errors.Join(errs...)
+}
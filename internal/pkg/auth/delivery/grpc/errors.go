@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"refresh/pkg/myerrors"
+)
+
+const errorDomain = "auth"
+
+// toStatus maps usecase errors to gRPC statuses using the codes registered
+// in pkg/myerrors; the stable code travels as the ErrorInfo reason. Unknown
+// errors are reported as Internal without their message so implementation
+// details do not leak.
+func toStatus(err error) error {
+	code, httpStatus, ok := myerrors.Lookup(err)
+	if !ok {
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	grpcCode := codes.InvalidArgument
+	switch httpStatus {
+	case http.StatusUnauthorized:
+		grpcCode = codes.Unauthenticated
+	case http.StatusForbidden:
+		grpcCode = codes.PermissionDenied
+	}
+
+	st, detailErr := status.New(grpcCode, err.Error()).WithDetails(&errdetails.ErrorInfo{
+		Reason: code,
+		Domain: errorDomain,
+	})
+	if detailErr != nil {
+		return status.Error(grpcCode, err.Error())
+	}
+	return st.Err()
+}
@@ -0,0 +1,176 @@
+package grpc
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.uber.org/fx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log/slog"
+	"net/http"
+	authv1 "refresh/api/auth/v1"
+	"refresh/internal/models"
+	"refresh/internal/pkg/audit"
+	"refresh/internal/pkg/auth"
+	"refresh/pkg/myerrors"
+)
+
+type Params struct {
+	fx.In
+
+	Usecase auth.Usecase
+	Logger  *slog.Logger
+}
+
+type Handler struct {
+	authv1.UnimplementedAuthServiceServer
+
+	uc  auth.Usecase
+	log *slog.Logger
+}
+
+func New(p Params) *Handler {
+	return &Handler{uc: p.Usecase, log: p.Logger}
+}
+
+func (h *Handler) Authenticate(ctx context.Context, req *authv1.AuthenticateRequest) (*authv1.TokenPair, error) {
+	ctx = withAuditClient(ctx)
+
+	id, err := uuid.Parse(req.GetUserId())
+	if err != nil {
+		h.log.Error("invalid user id", "error", err)
+		return nil, status.Error(codes.InvalidArgument, "uncorrected id")
+	}
+
+	tokens, err := h.uc.Authenticate(ctx, &models.TokenPayload{
+		UserID: id,
+		UserIP: clientIP(ctx),
+		Scopes: req.GetScopes(),
+	})
+	if err != nil {
+		h.log.Error("authenticate", "error", err)
+		return nil, toStatus(err)
+	}
+
+	return toTokenPair(tokens), nil
+}
+
+func (h *Handler) Refresh(ctx context.Context, req *authv1.RefreshRequest) (*authv1.TokenPair, error) {
+	ctx = withAuditClient(ctx)
+
+	if req.GetRefreshToken() == "" {
+		h.log.Error("refresh token in request not found")
+		return nil, status.Error(codes.Unauthenticated, "refresh token not found")
+	}
+
+	tokens, err := h.uc.Refresh(ctx, req.GetRefreshToken(), clientIP(ctx), req.GetScopes())
+	if err != nil {
+		h.log.Error("refresh", "error", err)
+		return nil, toStatus(err)
+	}
+
+	return toTokenPair(tokens), nil
+}
+
+// Logout revokes the given token. Like the HTTP revocation endpoint it
+// succeeds for tokens that are already unusable.
+func (h *Handler) Logout(ctx context.Context, req *authv1.LogoutRequest) (*authv1.LogoutResponse, error) {
+	ctx = withAuditClient(ctx)
+
+	if req.GetToken() == "" {
+		h.log.Error("token in logout request not found")
+		return nil, status.Error(codes.InvalidArgument, "token not found")
+	}
+
+	if err := h.uc.Revoke(ctx, req.GetToken(), req.GetTokenTypeHint()); err != nil {
+		// Errors registered with 401 mean the token is already unusable.
+		if _, status, _ := myerrors.Lookup(err); status != http.StatusUnauthorized {
+			h.log.Error("logout", "error", err)
+			return nil, toStatus(err)
+		}
+		h.log.Info("logout: token is already unusable", "error", err)
+	}
+
+	return &authv1.LogoutResponse{}, nil
+}
+
+func (h *Handler) Validate(ctx context.Context, req *authv1.ValidateRequest) (*authv1.ValidateResponse, error) {
+	payload, err := h.uc.Validate(ctx, req.GetAccessToken())
+	if err != nil {
+		h.log.Error("validate", "error", err)
+		return nil, toStatus(err)
+	}
+
+	return &authv1.ValidateResponse{
+		TokenId:        payload.ID.String(),
+		UserId:         payload.UserID.String(),
+		UserIp:         payload.UserIP,
+		Scopes:         payload.Scopes,
+		Roles:          payload.Roles,
+		ExpiresAt:      timestamppb.New(payload.Exp),
+		CertThumbprint: payload.CertThumbprint,
+		KeyThumbprint:  payload.KeyThumbprint,
+	}, nil
+}
+
+func (h *Handler) ListSessions(ctx context.Context, req *authv1.ListSessionsRequest) (*authv1.ListSessionsResponse, error) {
+	id, err := uuid.Parse(req.GetUserId())
+	if err != nil {
+		h.log.Error("invalid user id", "error", err)
+		return nil, status.Error(codes.InvalidArgument, "uncorrected id")
+	}
+
+	if !mayReadSessions(ctx, id) {
+		h.log.Error("sessions of another user requested", "user_id", id)
+		return nil, status.Error(codes.PermissionDenied, "insufficient scope")
+	}
+
+	sessions, err := h.uc.ListSessions(ctx, id)
+	if err != nil {
+		h.log.Error("list sessions", "error", err)
+		return nil, toStatus(err)
+	}
+
+	resp := &authv1.ListSessionsResponse{Sessions: make([]*authv1.Session, 0, len(sessions))}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &authv1.Session{
+			SessionId: session.SessionID.String(),
+			UserId:    session.UserID.String(),
+			UserIp:    session.UserIP,
+			CreatedAt: timestamppb.New(session.CreatedAt),
+		})
+	}
+
+	return resp, nil
+}
+
+func toTokenPair(tokens *models.PairToken) *authv1.TokenPair {
+	return &authv1.TokenPair{
+		AccessToken:      tokens.AccessToken,
+		RefreshToken:     tokens.RefreshToken,
+		AccessExpiresAt:  timestamppb.New(tokens.ExpAccessToken),
+		RefreshExpiresAt: timestamppb.New(tokens.ExpRefreshToken),
+	}
+}
+
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	return p.Addr.String()
+}
+
+// withAuditClient attaches the peer address and user agent for the audit log.
+func withAuditClient(ctx context.Context) context.Context {
+	var userAgent string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+	return audit.WithClient(ctx, clientIP(ctx), userAgent)
+}
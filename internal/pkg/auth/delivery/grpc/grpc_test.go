@@ -0,0 +1,312 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	authv1 "refresh/api/auth/v1"
+	"refresh/internal/models"
+	mock_auth "refresh/internal/pkg/auth/mocks"
+	"refresh/pkg/logger"
+	"refresh/pkg/myerrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, uc *mock_auth.MockUsecase) authv1.AuthServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv, err := NewServer(ServerParams{Handler: &Handler{uc: uc, log: logger.SetupLogger()}})
+	require.NoError(t, err)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return authv1.NewAuthServiceClient(conn)
+}
+
+func TestHandler_Authenticate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_auth.NewMockUsecase(ctrl)
+	client := newTestClient(t, mockUsecase)
+	userID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	tests := []struct {
+		name         string
+		userID       string
+		setupMocks   func()
+		expectedCode codes.Code
+	}{
+		{
+			name:   "Success case",
+			userID: userID.String(),
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Authenticate(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payload *models.TokenPayload) (*models.PairToken, error) {
+						assert.Equal(t, userID, payload.UserID)
+						assert.NotEmpty(t, payload.UserIP)
+						return &models.PairToken{
+							AccessToken:     "access_token",
+							RefreshToken:    "refresh_token",
+							ExpRefreshToken: time.Now().Add(time.Hour),
+						}, nil
+					})
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Invalid id format",
+			userID:       "invalid-uuid",
+			setupMocks:   func() {},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:   "Error from Usecase",
+			userID: userID.String(),
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Authenticate(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("usecase error"))
+			},
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			_, err := client.Authenticate(context.Background(), &authv1.AuthenticateRequest{UserId: tt.userID})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestHandler_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_auth.NewMockUsecase(ctrl)
+	client := newTestClient(t, mockUsecase)
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		setupMocks   func()
+		expectedCode codes.Code
+	}{
+		{
+			name:         "Success case",
+			refreshToken: "valid_refresh_token",
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Refresh(gomock.Any(), "valid_refresh_token", gomock.Any(), gomock.Any()).
+					Return(&models.PairToken{AccessToken: "access_token", RefreshToken: "refresh_token"}, nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Missing token",
+			refreshToken: "",
+			setupMocks:   func() {},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Expired token",
+			refreshToken: "expired_refresh_token",
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Refresh(gomock.Any(), "expired_refresh_token", gomock.Any(), gomock.Any()).
+					Return(nil, myerrors.ErrTokenExpired)
+			},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Scope not granted",
+			refreshToken: "valid_refresh_token",
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Refresh(gomock.Any(), "valid_refresh_token", gomock.Any(), gomock.Any()).
+					Return(nil, myerrors.ErrInvalidScope)
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			_, err := client.Refresh(context.Background(), &authv1.RefreshRequest{RefreshToken: tt.refreshToken})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestHandler_Logout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_auth.NewMockUsecase(ctrl)
+	client := newTestClient(t, mockUsecase)
+
+	mockUsecase.EXPECT().
+		Revoke(gomock.Any(), "invalid_token", models.TokenTypeHintRefresh).
+		Return(myerrors.ErrInvalidToken)
+
+	_, err := client.Logout(context.Background(), &authv1.LogoutRequest{
+		Token:         "invalid_token",
+		TokenTypeHint: models.TokenTypeHintRefresh,
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestHandler_Validate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_auth.NewMockUsecase(ctrl)
+	client := newTestClient(t, mockUsecase)
+
+	payload := &models.TokenPayload{
+		ID:     uuid.New(),
+		UserID: uuid.New(),
+		UserIP: "127.0.0.1:8080",
+		Exp:    time.Now().Add(time.Minute).Truncate(time.Second),
+		Scopes: []string{"orders:read"},
+		Roles:  []string{"admin"},
+	}
+	mockUsecase.EXPECT().Validate(gomock.Any(), "access_token").Return(payload, nil)
+	mockUsecase.EXPECT().Validate(gomock.Any(), "revoked_token").Return(nil, myerrors.ErrTokenRevoked)
+
+	resp, err := client.Validate(context.Background(), &authv1.ValidateRequest{AccessToken: "access_token"})
+	require.NoError(t, err)
+	assert.Equal(t, payload.UserID.String(), resp.GetUserId())
+	assert.Equal(t, payload.Scopes, resp.GetScopes())
+	assert.True(t, payload.Exp.Equal(resp.GetExpiresAt().AsTime()))
+
+	_, err = client.Validate(context.Background(), &authv1.ValidateRequest{AccessToken: "revoked_token"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestHandler_ListSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_auth.NewMockUsecase(ctrl)
+	client := newTestClient(t, mockUsecase)
+	userID, sessionID := uuid.New(), uuid.New()
+	sessions := []models.Session{{SessionID: sessionID, UserID: userID, UserIP: "127.0.0.1:8080", CreatedAt: time.Now()}}
+
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		setupMocks   func()
+		expectedCode codes.Code
+	}{
+		{
+			name: "Success case",
+			ctx:  withToken("access_token"),
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Validate(gomock.Any(), "access_token").
+					Return(&models.TokenPayload{UserID: userID, Scopes: []string{SessionsReadScope}}, nil)
+				mockUsecase.EXPECT().ListSessions(gomock.Any(), userID).Return(sessions, nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name: "Sessions of another user",
+			ctx:  withToken("access_token"),
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Validate(gomock.Any(), "access_token").
+					Return(&models.TokenPayload{UserID: uuid.New(), Scopes: []string{SessionsReadScope}}, nil)
+			},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name: "Sessions of another user with admin scope",
+			ctx:  withToken("access_token"),
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Validate(gomock.Any(), "access_token").
+					Return(&models.TokenPayload{UserID: uuid.New(), Scopes: []string{SessionsReadScope, SessionsAdminScope}}, nil)
+				mockUsecase.EXPECT().ListSessions(gomock.Any(), userID).Return(sessions, nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Missing token",
+			ctx:          context.Background(),
+			setupMocks:   func() {},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name: "Invalid token",
+			ctx:  withToken("refresh_token"),
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Validate(gomock.Any(), "refresh_token").
+					Return(nil, myerrors.ErrInvalidToken)
+			},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name: "Insufficient scope",
+			ctx:  withToken("access_token"),
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Validate(gomock.Any(), "access_token").
+					Return(&models.TokenPayload{UserID: uuid.New(), Scopes: []string{"profile"}}, nil)
+			},
+			expectedCode: codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			resp, err := client.ListSessions(tt.ctx, &authv1.ListSessionsRequest{UserId: userID.String()})
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expectedCode == codes.OK {
+				require.Len(t, resp.GetSessions(), 1)
+				assert.Equal(t, sessionID.String(), resp.GetSessions()[0].GetSessionId())
+				assert.Equal(t, "127.0.0.1:8080", resp.GetSessions()[0].GetUserIp())
+			}
+		})
+	}
+}
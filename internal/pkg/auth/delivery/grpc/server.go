@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/fx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"log/slog"
+	"net"
+	authv1 "refresh/api/auth/v1"
+	"refresh/internal/pkg/mtls"
+)
+
+type ServerParams struct {
+	fx.In
+
+	Config    Config
+	Handler   *Handler
+	Logger    *slog.Logger
+	Lifecycle fx.Lifecycle
+}
+
+func NewServer(p ServerParams) (*grpc.Server, error) {
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(mtls.UnaryServerInterceptor, p.Handler.authInterceptor)}
+
+	if p.Config.TLS.Enabled {
+		certs, err := mtls.NewReloader(p.Config.TLS, p.Logger)
+		if err != nil {
+			return nil, err
+		}
+		p.Lifecycle.Append(fx.StartStopHook(certs.Start, certs.Stop))
+		opts = append(opts, grpc.Creds(credentials.NewTLS(certs.TLSConfig())))
+	}
+
+	srv := grpc.NewServer(opts...)
+	authv1.RegisterAuthServiceServer(srv, p.Handler)
+	return srv, nil
+}
+
+type RunParams struct {
+	fx.In
+
+	Config    Config
+	Server    *grpc.Server
+	Logger    *slog.Logger
+	Lifecycle fx.Lifecycle
+}
+
+func RunServer(p RunParams) {
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			lis, err := net.Listen("tcp", p.Config.Address)
+			if err != nil {
+				return fmt.Errorf("listen grpc: %w", err)
+			}
+
+			go func() {
+				if err := p.Server.Serve(lis); err != nil {
+					p.Logger.Error("serve grpc", "error", err)
+				}
+			}()
+			p.Logger.Info("grpc server started", "address", p.Config.Address)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, p.Config.ShutdownTimeout)
+			defer cancel()
+
+			stopped := make(chan struct{})
+			go func() {
+				p.Server.GracefulStop()
+				close(stopped)
+			}()
+
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				p.Server.Stop()
+			}
+
+			return nil
+		},
+	})
+}
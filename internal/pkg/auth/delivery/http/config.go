@@ -0,0 +1,133 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"refresh/pkg/dpop"
+	"strings"
+)
+
+const hostCookiePrefix = "__Host-"
+
+// CookieConfig controls the attributes of the refresh token cookie.
+type CookieConfig struct {
+	Name string `yaml:"name" env-default:"refresh_token"`
+	// HostPrefix prepends "__Host-" to Name, which browsers only accept for
+	// Secure cookies with Path "/" and no Domain.
+	HostPrefix bool   `yaml:"hostPrefix"`
+	Domain     string `yaml:"domain"`
+	Path       string `yaml:"path" env-default:"/api/v1/auth"`
+	// Secure has no env-default: cleanenv would apply it over an explicit
+	// false, so the shipped config sets it.
+	Secure bool `yaml:"secure"`
+	// SameSite is one of strict, lax or none.
+	SameSite string `yaml:"sameSite" env-default:"strict"`
+}
+
+func (c CookieConfig) name() string {
+	name := c.Name
+	if name == "" {
+		name = RefreshCookieName
+	}
+	if c.HostPrefix {
+		return hostCookiePrefix + name
+	}
+	return name
+}
+
+func (c CookieConfig) Validate() error {
+	if c.HostPrefix && (!c.Secure || c.Path != "/" || c.Domain != "") {
+		return errors.New("refresh cookie: __Host- prefix requires secure, path \"/\" and no domain")
+	}
+	sameSite, err := parseSameSite(c.SameSite)
+	if err != nil {
+		return err
+	}
+	if sameSite == http.SameSiteNoneMode && !c.Secure {
+		return errors.New("refresh cookie: SameSite=None requires secure")
+	}
+	return nil
+}
+
+func parseSameSite(raw string) (http.SameSite, error) {
+	switch strings.ToLower(raw) {
+	case "":
+		return http.SameSiteDefaultMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("refresh cookie: unknown SameSite %q", raw)
+	}
+}
+
+// Refresh token delivery modes.
+const (
+	// DeliveryCookie keeps the refresh token in an HttpOnly cookie and out of
+	// the JSON response, so browser scripts never see it.
+	DeliveryCookie = "cookie"
+	// DeliveryBody returns the refresh token in the JSON response and accepts
+	// it from a JSON body or an Authorization: Bearer header.
+	DeliveryBody = "body"
+	// DeliveryBoth does both and accepts the token from any source.
+	DeliveryBoth = "both"
+)
+
+// DeliveryConfig selects how refresh tokens travel. Clients identify
+// themselves with the X-Client-ID header; unknown or anonymous clients get
+// Mode. The header is not authenticated, so browsers and clients holding
+// the refresh cookie always get Mode.
+type DeliveryConfig struct {
+	Mode    string            `yaml:"mode" env-default:"cookie"`
+	Clients map[string]string `yaml:"clients"`
+}
+
+func (c DeliveryConfig) Validate() error {
+	if c.Mode != "" {
+		if err := validateDeliveryMode(c.Mode); err != nil {
+			return err
+		}
+	}
+	for client, mode := range c.Clients {
+		if err := validateDeliveryMode(mode); err != nil {
+			return fmt.Errorf("client %s: %w", client, err)
+		}
+	}
+	return nil
+}
+
+func (c DeliveryConfig) mode(clientID string) string {
+	if mode, ok := c.Clients[clientID]; ok && clientID != "" {
+		return mode
+	}
+	if c.Mode == "" {
+		return DeliveryCookie
+	}
+	return c.Mode
+}
+
+func validateDeliveryMode(mode string) error {
+	switch mode {
+	case DeliveryCookie, DeliveryBody, DeliveryBoth:
+		return nil
+	default:
+		return fmt.Errorf("refresh token delivery: unknown mode %q", mode)
+	}
+}
+
+// DPoPConfig controls DPoP proofs (RFC 9449) at the token endpoints. Tokens
+// issued or refreshed with a valid proof are bound to its key.
+type DPoPConfig struct {
+	// Required rejects token requests without a proof. Otherwise only
+	// clients that send one get bound tokens.
+	Required bool        `yaml:"required"`
+	Proof    dpop.Config `yaml:"proof"`
+}
+
+func (c DPoPConfig) Validate() error {
+	return c.Proof.Validate()
+}
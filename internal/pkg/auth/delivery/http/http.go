@@ -1,33 +1,90 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"go.uber.org/fx"
+	"io"
 	"log/slog"
 	"net/http"
 	"refresh/internal/models"
+	"refresh/internal/pkg/audit"
 	"refresh/internal/pkg/auth"
+	"refresh/pkg/dpop"
+	"refresh/pkg/logger"
 	"refresh/pkg/myerrors"
 	"refresh/pkg/responser"
+	"strings"
+	"sync"
 )
 
+// RefreshCookieName is the refresh cookie name when CookieConfig.Name is
+// empty.
 const RefreshCookieName = "refresh_token"
 
+const scopeParam = "scope"
+
+const (
+	clientIDHeader = "X-Client-ID"
+	// fetchSiteHeader is sent by browsers and cannot be set by scripts.
+	fetchSiteHeader = "Sec-Fetch-Site"
+
+	maxRefreshBodyBytes = 1 << 16
+)
+
+const (
+	revokeTokenParam     = "token"
+	revokeTokenTypeParam = "token_type_hint"
+
+	oauthErrInvalidRequest = "invalid_request"
+)
+
 type Params struct {
 	fx.In
 
-	Usecase auth.Usecase
-	Logger  *slog.Logger
+	Usecase  auth.Usecase
+	Cookie   CookieConfig
+	Delivery DeliveryConfig
+	DPoP     DPoPConfig
+	Logger   *slog.Logger
 }
 
 type Handler struct {
-	uc  auth.Usecase
-	log *slog.Logger
+	uc       auth.Usecase
+	cookie   CookieConfig
+	sameSite http.SameSite
+	mu       sync.RWMutex
+	delivery DeliveryConfig
+	dpop     DPoPConfig
+	proofs   *dpop.Verifier
+	log      *slog.Logger
 }
 
-func New(p Params) *Handler {
-	return &Handler{uc: p.Usecase, log: p.Logger}
+func New(p Params) (*Handler, error) {
+	if err := p.Cookie.Validate(); err != nil {
+		return nil, err
+	}
+	if err := p.Delivery.Validate(); err != nil {
+		return nil, err
+	}
+	sameSite, _ := parseSameSite(p.Cookie.SameSite)
+	proofs, err := dpop.NewVerifier(p.DPoP.Proof)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		uc:       p.Usecase,
+		cookie:   p.Cookie,
+		sameSite: sameSite,
+		delivery: p.Delivery,
+		dpop:     p.DPoP,
+		proofs:   proofs,
+		log:      p.Logger,
+	}, nil
 }
 
 func (h *Handler) Authenticate(w http.ResponseWriter, r *http.Request) {
@@ -35,71 +92,260 @@ func (h *Handler) Authenticate(w http.ResponseWriter, r *http.Request) {
 	clientIP := r.RemoteAddr
 
 	if userID == "" {
-		h.log.Error("user id in query params not found")
+		h.logger(r).Error("user id in query params not found")
 		responser.Send400(w, "id not found")
 		return
 	}
 
 	id, err := uuid.Parse(userID)
 	if err != nil {
-		h.log.Error("invalid user id", "error", err)
+		h.logger(r).Error("invalid user id", "error", err)
 		responser.Send400(w, "uncorrected id")
 		return
 	}
 
-	tokens, err := h.uc.Authenticate(r.Context(), &models.TokenPayload{UserID: id, UserIP: clientIP})
+	mode := h.deliveryMode(r)
+
+	ctx, err := h.withProof(audit.WithClient(r.Context(), clientIP, r.UserAgent()), r)
 	if err != nil {
-		h.log.Error("authenticate", "error", err)
-		responser.Send500(w)
+		h.logger(r).Error("dpop proof", "error", err)
+		responser.SendError(w, myerrors.ErrInvalidDPoPProof)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     RefreshCookieName,
-		Value:    tokens.RefreshToken,
-		Path:     "/",
-		Expires:  tokens.ExpRefreshToken,
-		HttpOnly: true,
+	tokens, err := h.uc.Authenticate(ctx, &models.TokenPayload{
+		UserID: id,
+		UserIP: clientIP,
+		Scopes: parseScope(r.URL.Query().Get(scopeParam)),
 	})
+	if err != nil {
+		h.logger(r).Error("authenticate", "error", err)
+		responser.SendError(w, err)
+		return
+	}
 
-	responser.Send200(w, tokens)
+	h.sendTokens(w, tokens, mode)
 }
 
 func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie(RefreshCookieName)
-	if err != nil || cookie == nil {
-		h.log.Error("invalid cookie", "error", err)
+	mode := h.deliveryMode(r)
+
+	refresh, err := h.refreshToken(r, mode)
+	if err != nil {
+		h.logger(r).Error("refresh token not found", "error", err)
 		responser.Send401(w, "refresh token not found")
 		return
 	}
-	var refresh = cookie.Value
 	clientIP := r.RemoteAddr
 
-	tokens, err := h.uc.Refresh(r.Context(), refresh, clientIP)
+	scopes := parseScope(r.URL.Query().Get(scopeParam))
+
+	ctx, err := h.withProof(audit.WithClient(r.Context(), clientIP, r.UserAgent()), r)
 	if err != nil {
-		h.log.Error("refresh", "error", err)
-		switch {
-		case errors.Is(err, myerrors.ErrInvalidToken):
-			responser.Send401(w, err.Error())
-			return
-		case errors.Is(err, myerrors.ErrInappropriateRefreshToken):
-			responser.Send401(w, err.Error())
-			return
-		case errors.Is(err, myerrors.ErrTokenExpired):
-			responser.Send401(w, err.Error())
-			return
-		default:
-			responser.Send500(w)
-			return
+		h.logger(r).Error("dpop proof", "error", err)
+		responser.SendError(w, myerrors.ErrInvalidDPoPProof)
+		return
+	}
+
+	tokens, err := h.uc.Refresh(ctx, refresh, clientIP, scopes)
+	if err != nil {
+		h.logger(r).Error("refresh", "error", err)
+		responser.SendError(w, err)
+		return
+	}
+	h.sendTokens(w, tokens, mode)
+}
+
+// SetDelivery validates cfg and applies it to the following requests.
+func (h *Handler) SetDelivery(cfg DeliveryConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.delivery = cfg
+	return nil
+}
+
+// deliveryMode returns the delivery of the client named by X-Client-ID.
+// A script can send any client ID, so the header is ignored for browsers
+// and for requests carrying the refresh cookie, which keeps an injected
+// script from getting the refresh token in a response body.
+func (h *Handler) deliveryMode(r *http.Request) string {
+	clientID := r.Header.Get(clientIDHeader)
+	if r.Header.Get(fetchSiteHeader) != "" {
+		clientID = ""
+	}
+	if _, err := r.Cookie(h.cookie.name()); err == nil {
+		clientID = ""
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.delivery.mode(clientID)
+}
+
+// withProof verifies the DPoP proof of r and stores it in ctx, where the
+// usecase picks it up to bind the issued tokens. Requests without a proof
+// pass unless proofs are required.
+func (h *Handler) withProof(ctx context.Context, r *http.Request) (context.Context, error) {
+	if r.Header.Get(dpop.HeaderName) == "" && !h.dpop.Required {
+		return ctx, nil
+	}
+
+	proof, err := h.proofs.VerifyRequest(r, "")
+	if err != nil {
+		return nil, err
+	}
+	return dpop.NewContext(ctx, proof), nil
+}
+
+// refreshToken reads the refresh token from the sources allowed by mode: the
+// cookie, and a JSON body or Authorization: Bearer header.
+func (h *Handler) refreshToken(r *http.Request, mode string) (string, error) {
+	if mode != DeliveryCookie {
+		if token, ok := bearerToken(r); ok {
+			return token, nil
+		}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			var body refreshRequest
+			err := json.NewDecoder(io.LimitReader(r.Body, maxRefreshBodyBytes)).Decode(&body)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return "", fmt.Errorf("decode refresh body: %w", err)
+			}
+			if body.RefreshToken != "" {
+				return body.RefreshToken, nil
+			}
 		}
 	}
+
+	if mode != DeliveryBody {
+		cookie, err := r.Cookie(h.cookie.name())
+		if err == nil && cookie.Value != "" {
+			return cookie.Value, nil
+		}
+	}
+
+	return "", errors.New("no refresh token in request")
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+// sendTokens delivers the pair as configured for the client. In cookie mode
+// the refresh token is left out of the JSON body.
+func (h *Handler) sendTokens(w http.ResponseWriter, tokens *models.PairToken, mode string) {
+	if mode != DeliveryBody {
+		h.setRefreshCookie(w, tokens)
+	}
+	if mode == DeliveryCookie {
+		body := *tokens
+		body.RefreshToken = ""
+		tokens = &body
+	}
+
+	responser.Send200(w, tokens)
+}
+
+func (h *Handler) setRefreshCookie(w http.ResponseWriter, tokens *models.PairToken) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     RefreshCookieName,
+		Name:     h.cookie.name(),
 		Value:    tokens.RefreshToken,
-		Path:     "/",
+		Path:     h.cookie.Path,
+		Domain:   h.cookie.Domain,
 		Expires:  tokens.ExpRefreshToken,
+		Secure:   h.cookie.Secure,
 		HttpOnly: true,
+		SameSite: h.sameSite,
 	})
+}
 
-	responser.Send200(w, tokens)
+func (h *Handler) clearRefreshCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cookie.name(),
+		Path:     h.cookie.Path,
+		Domain:   h.cookie.Domain,
+		MaxAge:   -1,
+		Secure:   h.cookie.Secure,
+		HttpOnly: true,
+		SameSite: h.sameSite,
+	})
+}
+
+// parseScope splits a space-delimited scope parameter (RFC 6749 section 3.3).
+func parseScope(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	return strings.Fields(raw)
+}
+
+// Revoke implements the RFC 7009 revocation endpoint. Invalid, expired or
+// already revoked tokens are answered with 200 so the response never reveals
+// whether the submitted token was valid. The refresh cookie is ignored, as
+// the endpoint is not behind CSRF protection.
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	h.revoke(w, r, false)
+}
+
+// Logout is Revoke for cookie clients and must be served behind CSRF
+// protection: posting without a token revokes and clears the refresh cookie.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	h.revoke(w, r, true)
+}
+
+func (h *Handler) revoke(w http.ResponseWriter, r *http.Request, withCookie bool) {
+	if err := r.ParseForm(); err != nil {
+		h.logger(r).Error("parse revoke form", "error", err)
+		responser.SendOAuthError(w, http.StatusBadRequest, oauthErrInvalidRequest)
+		return
+	}
+
+	token := r.PostForm.Get(revokeTokenParam)
+	hint := r.PostForm.Get(revokeTokenTypeParam)
+	var cookie *http.Cookie
+	if withCookie {
+		if c, err := r.Cookie(h.cookie.name()); err == nil && c.Value != "" {
+			cookie = c
+		}
+	}
+	if token == "" && cookie != nil {
+		token, hint = cookie.Value, models.TokenTypeHintRefresh
+	}
+	if token == "" {
+		h.logger(r).Error("token in revoke request not found")
+		responser.SendOAuthError(w, http.StatusBadRequest, oauthErrInvalidRequest)
+		return
+	}
+
+	ctx := audit.WithClient(r.Context(), r.RemoteAddr, r.UserAgent())
+	if err := h.uc.Revoke(ctx, token, hint); err != nil {
+		// Errors registered with 401 mean the token is already unusable.
+		if _, status, _ := myerrors.Lookup(err); status != http.StatusUnauthorized {
+			h.logger(r).Error("revoke", "error", err)
+			responser.SendError(w, err)
+			return
+		}
+		h.logger(r).Info("revoke: token is already unusable", "error", err)
+	}
+
+	if cookie != nil && cookie.Value == token {
+		h.clearRefreshCookie(w)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) logger(r *http.Request) *slog.Logger {
+	return logger.FromContext(r.Context(), h.log)
 }
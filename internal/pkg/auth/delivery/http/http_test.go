@@ -1,16 +1,19 @@
 package http
 
 import (
-	"context"
+	"encoding/json"
 	"errors"
 	"github.com/google/uuid"
 	"go.uber.org/mock/gomock"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"refresh/internal/models"
 	mock_auth "refresh/internal/pkg/auth/mocks"
 	"refresh/pkg/logger"
 	"refresh/pkg/myerrors"
+	"strings"
 	"testing"
 	"time"
 
@@ -38,7 +41,7 @@ func TestHandler_Authenticate(t *testing.T) {
 			query: "?id=550e8400-e29b-41d4-a716-446655440000",
 			setupMocks: func() {
 				mockUsecase.EXPECT().
-					Authenticate(context.Background(), &models.TokenPayload{
+					Authenticate(gomock.Any(), &models.TokenPayload{
 						UserID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"),
 						UserIP: "127.0.0.1:8080",
 					}).
@@ -62,6 +65,20 @@ func TestHandler_Authenticate(t *testing.T) {
 			setupMocks:   func() {},
 			expectedCode: http.StatusBadRequest,
 		},
+		{
+			name:  "Scope not granted",
+			query: "?id=550e8400-e29b-41d4-a716-446655440000&scope=admin%20read",
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Authenticate(gomock.Any(), &models.TokenPayload{
+						UserID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"),
+						UserIP: "127.0.0.1:8080",
+						Scopes: []string{"admin", "read"},
+					}).
+					Return(nil, myerrors.ErrInvalidScope)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
 		{
 			name:  "Error from Usecase",
 			query: "?id=550e8400-e29b-41d4-a716-446655440000",
@@ -111,7 +128,7 @@ func TestHandler_Refresh(t *testing.T) {
 			},
 			setupMocks: func() {
 				mockUsecase.EXPECT().
-					Refresh(context.Background(), "valid_refresh_token", "127.0.0.1:8080").
+					Refresh(gomock.Any(), "valid_refresh_token", "127.0.0.1:8080", nil).
 					Return(&models.PairToken{
 						AccessToken:     "access_token",
 						RefreshToken:    "refresh_token",
@@ -134,11 +151,24 @@ func TestHandler_Refresh(t *testing.T) {
 			},
 			setupMocks: func() {
 				mockUsecase.EXPECT().
-					Refresh(gomock.Any(), "invalid_refresh_token", gomock.Any()).
+					Refresh(gomock.Any(), "invalid_refresh_token", gomock.Any(), gomock.Any()).
 					Return(nil, myerrors.ErrInvalidToken)
 			},
 			expectedCode: http.StatusUnauthorized,
 		},
+		{
+			name: "Revoked refresh token",
+			cookie: &http.Cookie{
+				Name:  RefreshCookieName,
+				Value: "revoked_refresh_token",
+			},
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Refresh(gomock.Any(), "revoked_refresh_token", gomock.Any(), gomock.Any()).
+					Return(nil, myerrors.ErrTokenRevoked)
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
 		{
 			name: "Unexpected error",
 			cookie: &http.Cookie{
@@ -147,7 +177,7 @@ func TestHandler_Refresh(t *testing.T) {
 			},
 			setupMocks: func() {
 				mockUsecase.EXPECT().
-					Refresh(gomock.Any(), gomock.Any(), gomock.Any()).
+					Refresh(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(nil, errors.New("unexpected error"))
 			},
 			expectedCode: http.StatusInternalServerError,
@@ -170,3 +200,351 @@ func TestHandler_Refresh(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_Revoke(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_auth.NewMockUsecase(ctrl)
+	handler := &Handler{
+		uc:  mockUsecase,
+		log: logger.SetupLogger(),
+	}
+	tests := []struct {
+		name         string
+		form         url.Values
+		cookie       *http.Cookie
+		logout       bool
+		setupMocks   func()
+		expectedCode int
+		wantCleared  bool
+	}{
+		{
+			name: "Success case",
+			form: url.Values{"token": {"valid_token"}, "token_type_hint": {"refresh_token"}},
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Revoke(gomock.Any(), "valid_token", "refresh_token").
+					Return(nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Missing token",
+			form:         url.Values{},
+			setupMocks:   func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Refresh cookie is ignored without logout",
+			form:         url.Values{},
+			cookie:       &http.Cookie{Name: RefreshCookieName, Value: "cookie_token"},
+			setupMocks:   func() {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Refresh cookie is revoked and cleared",
+			form:   url.Values{},
+			cookie: &http.Cookie{Name: RefreshCookieName, Value: "cookie_token"},
+			logout: true,
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Revoke(gomock.Any(), "cookie_token", "refresh_token").
+					Return(nil)
+			},
+			expectedCode: http.StatusOK,
+			wantCleared:  true,
+		},
+		{
+			name:   "Unusable refresh cookie is cleared",
+			form:   url.Values{},
+			cookie: &http.Cookie{Name: RefreshCookieName, Value: "stale_token"},
+			logout: true,
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Revoke(gomock.Any(), "stale_token", "refresh_token").
+					Return(myerrors.ErrInappropriateRefreshToken)
+			},
+			expectedCode: http.StatusOK,
+			wantCleared:  true,
+		},
+		{
+			name: "Invalid token",
+			form: url.Values{"token": {"invalid_token"}},
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Revoke(gomock.Any(), "invalid_token", "").
+					Return(myerrors.ErrInvalidToken)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "Expired token",
+			form: url.Values{"token": {"expired_token"}, "token_type_hint": {"access_token"}},
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Revoke(gomock.Any(), "expired_token", "access_token").
+					Return(myerrors.ErrTokenExpired)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "Unexpected error",
+			form: url.Values{"token": {"valid_token"}},
+			setupMocks: func() {
+				mockUsecase.EXPECT().
+					Revoke(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("unexpected error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+			req := httptest.NewRequest(http.MethodPost, "/oauth/revoke", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			rec := httptest.NewRecorder()
+
+			if tt.logout {
+				handler.Logout(rec, req)
+			} else {
+				handler.Revoke(rec, req)
+			}
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			cookies := rec.Result().Cookies()
+			if tt.wantCleared && assert.Len(t, cookies, 1) {
+				assert.Equal(t, RefreshCookieName, cookies[0].Name)
+				assert.Negative(t, cookies[0].MaxAge)
+			} else if !tt.wantCleared {
+				assert.Empty(t, cookies)
+			}
+		})
+	}
+}
+
+func TestHandler_RefreshCookieAttributes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_auth.NewMockUsecase(ctrl)
+	handler, err := New(Params{
+		Usecase: mockUsecase,
+		Cookie: CookieConfig{
+			Name:       "refresh_token",
+			HostPrefix: true,
+			Path:       "/",
+			Secure:     true,
+			SameSite:   "strict",
+		},
+		Logger: logger.SetupLogger(),
+	})
+	assert.NoError(t, err)
+
+	mockUsecase.EXPECT().
+		Refresh(gomock.Any(), "valid_refresh_token", gomock.Any(), gomock.Any()).
+		Return(&models.PairToken{RefreshToken: "rotated", ExpRefreshToken: time.Now().Add(time.Hour)}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "__Host-refresh_token", Value: "valid_refresh_token"})
+	rec := httptest.NewRecorder()
+
+	handler.Refresh(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "__Host-refresh_token", cookies[0].Name)
+		assert.Equal(t, "rotated", cookies[0].Value)
+		assert.Equal(t, "/", cookies[0].Path)
+		assert.True(t, cookies[0].Secure)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	}
+}
+
+func TestNew_InvalidCookieConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie CookieConfig
+	}{
+		{"host prefix with path", CookieConfig{HostPrefix: true, Path: "/api/v1/auth/refresh", Secure: true}},
+		{"host prefix with domain", CookieConfig{HostPrefix: true, Path: "/", Domain: "example.com", Secure: true}},
+		{"samesite none without secure", CookieConfig{Path: "/", SameSite: "none"}},
+		{"unknown samesite", CookieConfig{Path: "/", SameSite: "loose"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Params{Cookie: tt.cookie, Logger: logger.SetupLogger()})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHandler_RefreshDelivery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_auth.NewMockUsecase(ctrl)
+	handler, err := New(Params{
+		Usecase: mockUsecase,
+		Cookie:  CookieConfig{Path: "/api/v1/auth/refresh"},
+		Delivery: DeliveryConfig{
+			Mode:    DeliveryCookie,
+			Clients: map[string]string{"mobile": DeliveryBody},
+		},
+		Logger: logger.SetupLogger(),
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		clientID         string
+		prepare          func(r *http.Request)
+		expectedCode     int
+		wantCookie       bool
+		wantBodyRefresh  bool
+		expectUsecaseHit bool
+	}{
+		{
+			name:     "cookie mode hides refresh token from body",
+			clientID: "",
+			prepare: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: RefreshCookieName, Value: "refresh"})
+			},
+			expectedCode:     http.StatusOK,
+			wantCookie:       true,
+			expectUsecaseHit: true,
+		},
+		{
+			name:     "cookie mode ignores bearer header",
+			clientID: "",
+			prepare: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer refresh")
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "body mode accepts json body",
+			clientID: "mobile",
+			prepare: func(r *http.Request) {
+				r.Header.Set("Content-Type", "application/json")
+				r.Body = io.NopCloser(strings.NewReader(`{"refresh_token":"refresh"}`))
+			},
+			expectedCode:     http.StatusOK,
+			wantBodyRefresh:  true,
+			expectUsecaseHit: true,
+		},
+		{
+			name:     "body mode accepts bearer header",
+			clientID: "mobile",
+			prepare: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer refresh")
+			},
+			expectedCode:     http.StatusOK,
+			wantBodyRefresh:  true,
+			expectUsecaseHit: true,
+		},
+		{
+			name:     "client id does not widen delivery for a cookie holder",
+			clientID: "mobile",
+			prepare: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: RefreshCookieName, Value: "refresh"})
+			},
+			expectedCode:     http.StatusOK,
+			wantCookie:       true,
+			expectUsecaseHit: true,
+		},
+		{
+			name:     "client id does not widen delivery for a browser",
+			clientID: "mobile",
+			prepare: func(r *http.Request) {
+				r.Header.Set("Sec-Fetch-Site", "same-origin")
+				r.Header.Set("Authorization", "Bearer refresh")
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.expectUsecaseHit {
+				mockUsecase.EXPECT().
+					Refresh(gomock.Any(), "refresh", gomock.Any(), gomock.Any()).
+					Return(&models.PairToken{
+						AccessToken:     "access",
+						RefreshToken:    "rotated",
+						ExpRefreshToken: time.Now().Add(time.Hour),
+					}, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+			if tt.clientID != "" {
+				req.Header.Set("X-Client-ID", tt.clientID)
+			}
+			tt.prepare(req)
+			rec := httptest.NewRecorder()
+
+			handler.Refresh(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tt.wantCookie, len(rec.Result().Cookies()) == 1)
+
+			var body models.PairToken
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, "access", body.AccessToken)
+			if tt.wantBodyRefresh {
+				assert.Equal(t, "rotated", body.RefreshToken)
+			} else {
+				assert.Empty(t, body.RefreshToken)
+			}
+		})
+	}
+}
+
+func TestHandler_DPoPProof(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_auth.NewMockUsecase(ctrl)
+	handler, err := New(Params{
+		Usecase: mockUsecase,
+		Cookie:  CookieConfig{Path: "/api/v1/auth/refresh"},
+		DPoP:    DPoPConfig{Required: true},
+		Logger:  logger.SetupLogger(),
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		proof string
+	}{
+		{name: "Missing proof", proof: ""},
+		{name: "Malformed proof", proof: "not-a-jwt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/login?id=550e8400-e29b-41d4-a716-446655440000", nil)
+			if tt.proof != "" {
+				req.Header.Set("DPoP", tt.proof)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.Authenticate(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), myerrors.CodeInvalidDPoPProof)
+		})
+	}
+}
@@ -4,16 +4,34 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"refresh/internal/models"
+	"time"
 )
 
 //go:generate mockgen -source=interfaces.go -destination=mocks/mock.go
 
 type Usecase interface {
 	Authenticate(ctx context.Context, payload *models.TokenPayload) (*models.PairToken, error)
-	Refresh(ctx context.Context, refreshToken string, ip string) (*models.PairToken, error)
+	IssueAccessToken(ctx context.Context, payload *models.TokenPayload) (*models.PairToken, error)
+	Refresh(ctx context.Context, refreshToken string, ip string, scopes []string) (*models.PairToken, error)
+	Revoke(ctx context.Context, token string, tokenTypeHint string) error
+	Validate(ctx context.Context, accessToken string) (*models.TokenPayload, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	RevokeSessions(ctx context.Context, userID uuid.UUID) error
 }
 
 type Repository interface {
 	CheckToken(ctx context.Context, userID uuid.UUID, refreshToken string) error
 	CreateSession(ctx context.Context, session *models.Session) error
+	DeleteSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	GetPermissions(ctx context.Context, userID uuid.UUID) (*models.Permissions, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+}
+
+type Denylist interface {
+	Add(ctx context.Context, jti uuid.UUID, exp time.Time) error
+	Contains(ctx context.Context, jti uuid.UUID) (bool, error)
+}
+
+type Auditor interface {
+	Record(ctx context.Context, event *models.AuditEvent)
 }
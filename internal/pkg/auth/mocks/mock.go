@@ -13,6 +13,7 @@ import (
 	context "context"
 	reflect "reflect"
 	models "refresh/internal/models"
+	time "time"
 
 	uuid "github.com/google/uuid"
 	gomock "go.uber.org/mock/gomock"
@@ -57,19 +58,92 @@ func (mr *MockUsecaseMockRecorder) Authenticate(ctx, payload any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockUsecase)(nil).Authenticate), ctx, payload)
 }
 
+// IssueAccessToken mocks base method.
+func (m *MockUsecase) IssueAccessToken(ctx context.Context, payload *models.TokenPayload) (*models.PairToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueAccessToken", ctx, payload)
+	ret0, _ := ret[0].(*models.PairToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueAccessToken indicates an expected call of IssueAccessToken.
+func (mr *MockUsecaseMockRecorder) IssueAccessToken(ctx, payload any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueAccessToken", reflect.TypeOf((*MockUsecase)(nil).IssueAccessToken), ctx, payload)
+}
+
 // Refresh mocks base method.
-func (m *MockUsecase) Refresh(ctx context.Context, refreshToken, ip string) (*models.PairToken, error) {
+func (m *MockUsecase) Refresh(ctx context.Context, refreshToken, ip string, scopes []string) (*models.PairToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Refresh", ctx, refreshToken, ip)
+	ret := m.ctrl.Call(m, "Refresh", ctx, refreshToken, ip, scopes)
 	ret0, _ := ret[0].(*models.PairToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Refresh indicates an expected call of Refresh.
-func (mr *MockUsecaseMockRecorder) Refresh(ctx, refreshToken, ip any) *gomock.Call {
+func (mr *MockUsecaseMockRecorder) Refresh(ctx, refreshToken, ip, scopes any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockUsecase)(nil).Refresh), ctx, refreshToken, ip)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockUsecase)(nil).Refresh), ctx, refreshToken, ip, scopes)
+}
+
+// Revoke mocks base method.
+func (m *MockUsecase) Revoke(ctx context.Context, token, tokenTypeHint string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, token, tokenTypeHint)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockUsecaseMockRecorder) Revoke(ctx, token, tokenTypeHint any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockUsecase)(nil).Revoke), ctx, token, tokenTypeHint)
+}
+
+// Validate mocks base method.
+func (m *MockUsecase) Validate(ctx context.Context, accessToken string) (*models.TokenPayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Validate", ctx, accessToken)
+	ret0, _ := ret[0].(*models.TokenPayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Validate indicates an expected call of Validate.
+func (mr *MockUsecaseMockRecorder) Validate(ctx, accessToken any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockUsecase)(nil).Validate), ctx, accessToken)
+}
+
+// ListSessions mocks base method.
+func (m *MockUsecase) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, userID)
+	ret0, _ := ret[0].([]models.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockUsecaseMockRecorder) ListSessions(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockUsecase)(nil).ListSessions), ctx, userID)
+}
+
+// RevokeSessions mocks base method.
+func (m *MockUsecase) RevokeSessions(ctx context.Context, userID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessions", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSessions indicates an expected call of RevokeSessions.
+func (mr *MockUsecaseMockRecorder) RevokeSessions(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessions", reflect.TypeOf((*MockUsecase)(nil).RevokeSessions), ctx, userID)
 }
 
 // MockRepository is a mock of Repository interface.
@@ -123,3 +197,136 @@ func (mr *MockRepositoryMockRecorder) CreateSession(ctx, session any) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockRepository)(nil).CreateSession), ctx, session)
 }
+
+// DeleteSession mocks base method.
+func (m *MockRepository) DeleteSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSession", ctx, userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSession indicates an expected call of DeleteSession.
+func (mr *MockRepositoryMockRecorder) DeleteSession(ctx, userID, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockRepository)(nil).DeleteSession), ctx, userID, sessionID)
+}
+
+// GetPermissions mocks base method.
+func (m *MockRepository) GetPermissions(ctx context.Context, userID uuid.UUID) (*models.Permissions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPermissions", ctx, userID)
+	ret0, _ := ret[0].(*models.Permissions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPermissions indicates an expected call of GetPermissions.
+func (mr *MockRepositoryMockRecorder) GetPermissions(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissions", reflect.TypeOf((*MockRepository)(nil).GetPermissions), ctx, userID)
+}
+
+// ListSessions mocks base method.
+func (m *MockRepository) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, userID)
+	ret0, _ := ret[0].([]models.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockRepositoryMockRecorder) ListSessions(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockRepository)(nil).ListSessions), ctx, userID)
+}
+
+// MockDenylist is a mock of Denylist interface.
+type MockDenylist struct {
+	ctrl     *gomock.Controller
+	recorder *MockDenylistMockRecorder
+	isgomock struct{}
+}
+
+// MockDenylistMockRecorder is the mock recorder for MockDenylist.
+type MockDenylistMockRecorder struct {
+	mock *MockDenylist
+}
+
+// NewMockDenylist creates a new mock instance.
+func NewMockDenylist(ctrl *gomock.Controller) *MockDenylist {
+	mock := &MockDenylist{ctrl: ctrl}
+	mock.recorder = &MockDenylistMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDenylist) EXPECT() *MockDenylistMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockDenylist) Add(ctx context.Context, jti uuid.UUID, exp time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", ctx, jti, exp)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockDenylistMockRecorder) Add(ctx, jti, exp any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockDenylist)(nil).Add), ctx, jti, exp)
+}
+
+// Contains mocks base method.
+func (m *MockDenylist) Contains(ctx context.Context, jti uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Contains", ctx, jti)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Contains indicates an expected call of Contains.
+func (mr *MockDenylistMockRecorder) Contains(ctx, jti any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Contains", reflect.TypeOf((*MockDenylist)(nil).Contains), ctx, jti)
+}
+
+// MockAuditor is a mock of Auditor interface.
+type MockAuditor struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditorMockRecorder
+	isgomock struct{}
+}
+
+// MockAuditorMockRecorder is the mock recorder for MockAuditor.
+type MockAuditorMockRecorder struct {
+	mock *MockAuditor
+}
+
+// NewMockAuditor creates a new mock instance.
+func NewMockAuditor(ctrl *gomock.Controller) *MockAuditor {
+	mock := &MockAuditor{ctrl: ctrl}
+	mock.recorder = &MockAuditorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditor) EXPECT() *MockAuditorMockRecorder {
+	return m.recorder
+}
+
+// Record mocks base method.
+func (m *MockAuditor) Record(ctx context.Context, event *models.AuditEvent) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Record", ctx, event)
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockAuditorMockRecorder) Record(ctx, event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockAuditor)(nil).Record), ctx, event)
+}
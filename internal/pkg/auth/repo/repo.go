@@ -6,34 +6,48 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 	"golang.org/x/crypto/bcrypt"
 	"log/slog"
 	"refresh/internal/models"
+	"refresh/internal/pkg/metrics"
 	"refresh/pkg/myerrors"
+	"time"
 )
 
 const (
 	checkToken    = `SELECT hash_token FROM sessions WHERE user_id = $1`
-	insertSession = `INSERT INTO sessions (hash_token, user_id) VALUES ($1, $2) ON CONFLICT (user_id) DO UPDATE SET hash_token = EXCLUDED.hash_token`
+	insertSession = `INSERT INTO sessions (hash_token, user_id, user_ip, session_id) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id) DO UPDATE SET hash_token = EXCLUDED.hash_token, user_ip = EXCLUDED.user_ip, session_id = EXCLUDED.session_id, created_at = now()`
+	deleteSession = `DELETE FROM sessions WHERE user_id = $1 AND session_id = $2`
+	deleteAll     = `DELETE FROM sessions WHERE user_id = $1`
+	selectSession = `SELECT session_id, user_id, user_ip, created_at FROM sessions WHERE user_id = $1`
+	selectRoles   = `SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`
+	selectScopes  = `SELECT DISTINCT rs.scope FROM user_roles ur JOIN role_scopes rs ON rs.role = ur.role WHERE ur.user_id = $1 ORDER BY rs.scope`
 )
 
 type Params struct {
 	fx.In
 
-	DB     *pgxpool.Pool
-	Logger *slog.Logger
+	DB             *pgxpool.Pool
+	Metrics        *metrics.Metrics
+	TracerProvider trace.TracerProvider
+	Logger         *slog.Logger
 }
 
 type Repo struct {
-	db  *pgxpool.Pool
-	log *slog.Logger
+	db     *pgxpool.Pool
+	m      *metrics.Metrics
+	tracer trace.Tracer
+	log    *slog.Logger
 }
 
 func New(p Params) *Repo {
 	return &Repo{
-		db:  p.DB,
-		log: p.Logger,
+		db:     p.DB,
+		m:      p.Metrics,
+		tracer: p.TracerProvider.Tracer("refresh/internal/pkg/auth/repo"),
+		log:    p.Logger,
 	}
 }
 
@@ -48,7 +62,12 @@ func (r *Repo) CheckToken(ctx context.Context, userID uuid.UUID, refreshToken st
 		return err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(hashToken), []byte(refreshToken)); err != nil {
+	_, span := r.tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+	start := time.Now()
+	err := bcrypt.CompareHashAndPassword([]byte(hashToken), []byte(refreshToken))
+	r.m.ObserveHash(metrics.HashOperationCompare, time.Since(start))
+	span.End()
+	if err != nil {
 		return myerrors.ErrInappropriateRefreshToken
 	}
 
@@ -56,8 +75,57 @@ func (r *Repo) CheckToken(ctx context.Context, userID uuid.UUID, refreshToken st
 }
 
 func (r *Repo) CreateSession(ctx context.Context, session *models.Session) error {
-	if _, err := r.db.Exec(ctx, insertSession, session.HashToken, session.UserID); err != nil {
+	if _, err := r.db.Exec(ctx, insertSession, session.HashToken, session.UserID, session.UserIP, session.SessionID); err != nil {
 		return err
 	}
 	return nil
 }
+
+// DeleteSession deletes the session of userID with sessionID, or every
+// session of the user when sessionID is uuid.Nil.
+func (r *Repo) DeleteSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	query, args := deleteSession, []any{userID, sessionID}
+	if sessionID == uuid.Nil {
+		query, args = deleteAll, []any{userID}
+	}
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repo) GetPermissions(ctx context.Context, userID uuid.UUID) (*models.Permissions, error) {
+	roles, err := r.selectStrings(ctx, selectRoles, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := r.selectStrings(ctx, selectScopes, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Permissions{Roles: roles, Scopes: scopes}, nil
+}
+
+func (r *Repo) selectStrings(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (r *Repo) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	rows, err := r.db.Query(ctx, selectSession, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Session, error) {
+		var session models.Session
+		err := row.Scan(&session.SessionID, &session.UserID, &session.UserIP, &session.CreatedAt)
+		return session, err
+	})
+}
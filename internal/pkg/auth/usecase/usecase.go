@@ -3,112 +3,515 @@ package usecase
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/gomail.v2"
 	"log/slog"
 	"refresh/internal/models"
 	"refresh/internal/pkg/auth"
+	"refresh/internal/pkg/metrics"
+	"refresh/internal/pkg/mtls"
 	"refresh/internal/pkg/tokenizer"
+	"refresh/internal/pkg/tracing"
+	"refresh/pkg/dpop"
+	"refresh/pkg/logger"
+	"refresh/pkg/myerrors"
+	"time"
 )
 
 type Params struct {
 	fx.In
 
-	Repo      auth.Repository
-	Tokenizer *tokenizer.Tokenizer
-	Logger    *slog.Logger
+	Repo           auth.Repository
+	Denylist       auth.Denylist
+	Auditor        auth.Auditor
+	Tokenizer      *tokenizer.Tokenizer
+	Metrics        *metrics.Metrics
+	TracerProvider trace.TracerProvider
+	Logger         *slog.Logger
 }
 
 type Usecase struct {
-	r   auth.Repository
-	t   *tokenizer.Tokenizer
-	log *slog.Logger
+	r      auth.Repository
+	d      auth.Denylist
+	a      auth.Auditor
+	t      *tokenizer.Tokenizer
+	m      *metrics.Metrics
+	tracer trace.Tracer
+	log    *slog.Logger
 }
 
 func New(p Params) *Usecase {
-	return &Usecase{r: p.Repo, t: p.Tokenizer, log: p.Logger}
+	return &Usecase{
+		r:      p.Repo,
+		d:      p.Denylist,
+		a:      p.Auditor,
+		t:      p.Tokenizer,
+		m:      p.Metrics,
+		tracer: p.TracerProvider.Tracer("refresh/internal/pkg/auth/usecase"),
+		log:    p.Logger,
+	}
 }
 
 func (uc *Usecase) Authenticate(ctx context.Context, payload *models.TokenPayload) (*models.PairToken, error) {
-	pair, err := uc.t.GeneratePairToken(payload)
+	ctx, span := uc.tracer.Start(ctx, "Usecase.Authenticate")
+	defer span.End()
+
+	event := &models.AuditEvent{Type: models.AuditEventLogin, UserID: payload.UserID, IP: payload.UserIP}
+	pair, err := uc.authenticate(ctx, payload)
+	if pair != nil {
+		event.SessionID = pair.SessionID
+	}
+	uc.audit(ctx, event, err)
+	uc.m.ObserveLogin(err)
+	tracing.RecordError(span, err)
+	return pair, err
+}
+
+func (uc *Usecase) authenticate(ctx context.Context, payload *models.TokenPayload) (*models.PairToken, error) {
+	if id, ok := mtls.FromContext(ctx); ok {
+		payload.CertThumbprint = id.Thumbprint
+	}
+	if proof, ok := dpop.FromContext(ctx); ok {
+		payload.KeyThumbprint = proof.Thumbprint
+	}
+
+	if err := uc.grant(ctx, payload); err != nil {
+		return nil, err
+	}
+
+	pair, err := uc.t.GeneratePairToken(ctx, payload)
 	if err != nil {
-		uc.log.Error("failed to generate pair token", "error", err)
+		uc.logger(ctx).Error("failed to generate pair token", "error", err)
 		return nil, err
 	}
 
-	hashRefreshToken := hashToken(pair.RefreshToken)
+	hashRefreshToken := uc.hashToken(ctx, pair.RefreshToken)
 
 	session := &models.Session{
+		SessionID: pair.SessionID,
 		UserID:    payload.UserID,
 		HashToken: hashRefreshToken,
+		UserIP:    payload.UserIP,
 	}
 
 	err = uc.r.CreateSession(ctx, session)
 	if err != nil {
-		uc.log.Error("failed to create session", "error", err)
+		uc.logger(ctx).Error("failed to create session", "error", err)
 		return nil, err
 	}
 
 	return pair, nil
 }
 
-func (uc *Usecase) Refresh(ctx context.Context, refreshToken string, ip string) (*models.PairToken, error) {
-	payload, err := uc.t.ValidateJWT(refreshToken)
+// IssueAccessToken issues an access token with the user's grants but no
+// session, so that tools can act as a user without replacing the session
+// the user is logged in with.
+func (uc *Usecase) IssueAccessToken(ctx context.Context, payload *models.TokenPayload) (*models.PairToken, error) {
+	ctx, span := uc.tracer.Start(ctx, "Usecase.IssueAccessToken")
+	defer span.End()
+
+	event := &models.AuditEvent{Type: models.AuditEventTokenIssued, UserID: payload.UserID, IP: payload.UserIP}
+	err := uc.grant(ctx, payload)
+	var pair *models.PairToken
+	if err == nil {
+		pair, err = uc.t.GenerateAccessToken(ctx, payload)
+	}
 	if err != nil {
-		uc.log.Error("failed to validate refresh token", "error", err)
+		uc.logger(ctx).Error("failed to issue access token", "error", err)
+	}
+	uc.audit(ctx, event, err)
+	tracing.RecordError(span, err)
+	return pair, err
+}
+
+// grant sets the roles of the user and the scopes of the payload: the
+// requested ones that are granted, or all granted ones when none are
+// requested.
+func (uc *Usecase) grant(ctx context.Context, payload *models.TokenPayload) error {
+	perms, err := uc.r.GetPermissions(ctx, payload.UserID)
+	if err != nil {
+		uc.logger(ctx).Error("failed to get permissions", "error", err)
+		return err
+	}
+
+	payload.Roles = perms.Roles
+	if len(payload.Scopes) == 0 {
+		payload.Scopes = perms.Scopes
+	} else {
+		payload.Scopes = narrowScopes(payload.Scopes, perms.Scopes)
+		if len(payload.Scopes) == 0 {
+			uc.logger(ctx).Error("requested scopes are not granted")
+			return myerrors.ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+func (uc *Usecase) Refresh(ctx context.Context, refreshToken string, ip string, scopes []string) (*models.PairToken, error) {
+	ctx, span := uc.tracer.Start(ctx, "Usecase.Refresh")
+	defer span.End()
+
+	event := &models.AuditEvent{Type: models.AuditEventRefresh, IP: ip}
+	pair, err := uc.refresh(ctx, refreshToken, ip, scopes, event)
+	if pair != nil {
+		event.SessionID = pair.SessionID
+	}
+	uc.audit(ctx, event, err)
+	uc.m.ObserveRefresh(err)
+	tracing.RecordError(span, err)
+	return pair, err
+}
+
+func (uc *Usecase) refresh(ctx context.Context, refreshToken string, ip string, scopes []string, event *models.AuditEvent) (*models.PairToken, error) {
+	payload, err := uc.t.ValidateJWT(ctx, refreshToken)
+	if err != nil {
+		uc.logger(ctx).Error("failed to validate refresh token", "error", err)
 		return nil, err
 	}
+	event.UserID = payload.UserID
+
+	// Untyped tokens predate the typ claim; only the current refresh token
+	// passes CheckToken below.
+	if payload.Use != models.TokenUseRefresh && payload.Use != "" {
+		uc.logger(ctx).Error("not a refresh token", "typ", payload.Use)
+		return nil, myerrors.ErrInvalidToken
+	}
+
+	if err = uc.checkDenylist(ctx, payload); err != nil {
+		return nil, err
+	}
+
+	if err = uc.checkBinding(ctx, payload); err != nil {
+		return nil, err
+	}
+	// Tokens issued before the client switched to mTLS or DPoP are bound now.
+	if id, ok := mtls.FromContext(ctx); ok && payload.CertThumbprint == "" {
+		payload.CertThumbprint = id.Thumbprint
+	}
+	if proof, ok := dpop.FromContext(ctx); ok && payload.KeyThumbprint == "" {
+		payload.KeyThumbprint = proof.Thumbprint
+	}
 
 	hashedToken := sha256.Sum256([]byte(refreshToken))
 	err = uc.r.CheckToken(ctx, payload.UserID, string(hashedToken[:]))
 	if err != nil {
-		uc.log.Error("token inappropriate", "error", err)
+		uc.logger(ctx).Error("token inappropriate", "error", err)
 		return nil, err
 	}
 
+	// A refresh may only narrow the scopes of the original grant, and roles
+	// revoked since the last refresh take their scopes with them.
+	granted := payload.Scopes
+	if len(scopes) > 0 {
+		granted = narrowScopes(scopes, granted)
+		if len(granted) == 0 {
+			uc.logger(ctx).Error("requested scopes exceed the original grant")
+			return nil, myerrors.ErrInvalidScope
+		}
+	}
+
+	perms, err := uc.r.GetPermissions(ctx, payload.UserID)
+	if err != nil {
+		uc.logger(ctx).Error("failed to get permissions", "error", err)
+		return nil, err
+	}
+	payload.Roles = perms.Roles
+	payload.Scopes = narrowScopes(granted, perms.Scopes)
+
 	if payload.UserIP != ip {
-		uc.log.Info("IP address did not match")
+		uc.logger(ctx).Info("IP address did not match")
+		uc.a.Record(ctx, &models.AuditEvent{
+			Type:      models.AuditEventIPChanged,
+			UserID:    payload.UserID,
+			IP:        ip,
+			SessionID: payload.SessionID,
+			Outcome:   models.AuditOutcomeSuccess,
+			Reason:    "previous ip " + payload.UserIP,
+		})
 		payload.UserIP = ip
-		uc.sendEmail()
+		uc.sendEmail(ctx)
 	}
 
-	pair, err := uc.t.GeneratePairToken(payload)
+	pair, err := uc.t.GeneratePairToken(ctx, payload)
 	if err != nil {
-		uc.log.Error("failed to generate pair token", "error", err)
+		uc.logger(ctx).Error("failed to generate pair token", "error", err)
 		return nil, err
 	}
 
-	hashRefreshToken := hashToken(pair.RefreshToken)
-	uc.log.Debug(hashRefreshToken)
+	hashRefreshToken := uc.hashToken(ctx, pair.RefreshToken)
 	session := &models.Session{
+		SessionID: pair.SessionID,
 		UserID:    payload.UserID,
 		HashToken: hashRefreshToken,
+		UserIP:    payload.UserIP,
 	}
 	err = uc.r.CreateSession(ctx, session)
 	if err != nil {
-		uc.log.Error("failed to create session", "error", err)
+		uc.logger(ctx).Error("failed to create session", "error", err)
 		return nil, err
 	}
 
 	return pair, nil
 }
 
-func hashToken(token string) string {
+func (uc *Usecase) Revoke(ctx context.Context, token string, tokenTypeHint string) error {
+	ctx, span := uc.tracer.Start(ctx, "Usecase.Revoke")
+	defer span.End()
+
+	event := &models.AuditEvent{Type: models.AuditEventRevoke}
+	err := uc.revoke(ctx, token, tokenTypeHint, event)
+	uc.audit(ctx, event, err)
+	uc.m.ObserveRevoke(err)
+	tracing.RecordError(span, err)
+	return err
+}
+
+func (uc *Usecase) revoke(ctx context.Context, token string, tokenTypeHint string, event *models.AuditEvent) error {
+	payload, err := uc.t.ValidateJWT(ctx, token)
+	if err != nil {
+		uc.logger(ctx).Error("failed to validate revoked token", "error", err)
+		return err
+	}
+	event.UserID = payload.UserID
+
+	if err = uc.checkDenylist(ctx, payload); err != nil {
+		return err
+	}
+
+	// Revoking any token of a session ends the whole session, except for a
+	// refresh token that was rotated away, which is rejected. The typ claim
+	// tells refresh tokens apart; for untyped tokens the hint does. Tokens
+	// issued before sessions had identifiers end it only as its current
+	// refresh token.
+	endSession := payload.SessionID != uuid.Nil
+	isRefresh := payload.Use == models.TokenUseRefresh
+	if isRefresh || (payload.Use == "" && tokenTypeHint != models.TokenTypeHintAccess) {
+		hashedToken := sha256.Sum256([]byte(token))
+		err = uc.r.CheckToken(ctx, payload.UserID, string(hashedToken[:]))
+		switch {
+		case err == nil:
+			endSession = true
+		case isRefresh || tokenTypeHint == models.TokenTypeHintRefresh || !errors.Is(err, myerrors.ErrInappropriateRefreshToken):
+			uc.logger(ctx).Error("token inappropriate", "error", err)
+			return err
+		}
+	}
+
+	if payload.ID != uuid.Nil {
+		err = uc.d.Add(ctx, payload.ID, payload.Exp)
+		if err != nil {
+			uc.logger(ctx).Error("failed to denylist token", "error", err)
+			return err
+		}
+	}
+
+	if !endSession {
+		return nil
+	}
+	event.SessionID = payload.SessionID
+
+	return uc.endSession(ctx, payload.UserID, payload.SessionID)
+}
+
+// RevokeSessions ends every session of userID.
+func (uc *Usecase) RevokeSessions(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := uc.tracer.Start(ctx, "Usecase.RevokeSessions")
+	defer span.End()
+
+	sessions, err := uc.r.ListSessions(ctx, userID)
+	if err == nil {
+		for _, session := range sessions {
+			if err = uc.endSession(ctx, userID, session.SessionID); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		uc.logger(ctx).Error("failed to revoke sessions", "error", err)
+	}
+
+	uc.audit(ctx, &models.AuditEvent{Type: models.AuditEventRevoke, UserID: userID}, err)
+	uc.m.ObserveRevoke(err)
+	tracing.RecordError(span, err)
+	return err
+}
+
+// endSession denylists the session's sid, which denies every token issued
+// in it, and deletes the session. Without a sid all sessions of the user
+// are deleted.
+func (uc *Usecase) endSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if sessionID != uuid.Nil {
+		// No token of the session outlives a refresh token issued now.
+		err := uc.d.Add(ctx, sessionID, time.Now().Add(uc.t.RefreshExpirationTime()))
+		if err != nil {
+			uc.logger(ctx).Error("failed to denylist session", "error", err)
+			return err
+		}
+	}
+
+	err := uc.r.DeleteSession(ctx, userID, sessionID)
+	if err != nil {
+		uc.logger(ctx).Error("failed to delete session", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// Validate accepts an unrevoked access token presented by its holder: a
+// bound token needs the certificate or DPoP proof of ctx to match it.
+func (uc *Usecase) Validate(ctx context.Context, accessToken string) (*models.TokenPayload, error) {
+	ctx, span := uc.tracer.Start(ctx, "Usecase.Validate")
+	defer span.End()
+
+	payload, err := uc.t.ValidateJWT(ctx, accessToken)
+	if err != nil {
+		uc.logger(ctx).Error("failed to validate access token", "error", err)
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	if payload.Use != models.TokenUseAccess {
+		uc.logger(ctx).Error("not an access token", "typ", payload.Use)
+		tracing.RecordError(span, myerrors.ErrInvalidToken)
+		return nil, myerrors.ErrInvalidToken
+	}
+
+	if err = uc.checkDenylist(ctx, payload); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	if err = uc.checkBinding(ctx, payload); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+func (uc *Usecase) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	sessions, err := uc.r.ListSessions(ctx, userID)
+	if err != nil {
+		uc.logger(ctx).Error("failed to list sessions", "error", err)
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// audit records event with the outcome of the operation it describes.
+func (uc *Usecase) audit(ctx context.Context, event *models.AuditEvent, err error) {
+	event.Outcome = models.AuditOutcomeSuccess
+	if err != nil {
+		event.Outcome = models.AuditOutcomeFailure
+		event.Reason = err.Error()
+	}
+	uc.a.Record(ctx, event)
+	if event.UserID != uuid.Nil {
+		logger.AddAttrs(ctx, "user_id", event.UserID)
+	}
+}
+
+func (uc *Usecase) logger(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, uc.log)
+}
+
+// checkDenylist rejects tokens revoked on their own by jti or together with
+// their session by sid.
+func (uc *Usecase) checkDenylist(ctx context.Context, payload *models.TokenPayload) error {
+	for _, id := range []uuid.UUID{payload.ID, payload.SessionID} {
+		if id == uuid.Nil {
+			continue
+		}
+
+		revoked, err := uc.d.Contains(ctx, id)
+		if err != nil {
+			uc.logger(ctx).Error("failed to check token denylist", "error", err)
+			return err
+		}
+		if revoked {
+			uc.logger(ctx).Error("token revoked", "jti", payload.ID, "sid", payload.SessionID)
+			return myerrors.ErrTokenRevoked
+		}
+	}
+
+	return nil
+}
+
+// checkBinding rejects certificate-bound tokens presented over a connection
+// without the certificate they are bound to (RFC 8705 section 3), and
+// DPoP-bound tokens presented without a proof signed by their key (RFC 9449
+// section 5).
+func (uc *Usecase) checkBinding(ctx context.Context, payload *models.TokenPayload) error {
+	if payload.CertThumbprint != "" {
+		id, ok := mtls.FromContext(ctx)
+		if !ok || subtle.ConstantTimeCompare([]byte(id.Thumbprint), []byte(payload.CertThumbprint)) != 1 {
+			uc.logger(ctx).Error("token presented with a different certificate", "jti", payload.ID)
+			return myerrors.ErrTokenBindingMismatch
+		}
+	}
+
+	if payload.KeyThumbprint != "" {
+		proof, ok := dpop.FromContext(ctx)
+		if !ok || subtle.ConstantTimeCompare([]byte(proof.Thumbprint), []byte(payload.KeyThumbprint)) != 1 {
+			uc.logger(ctx).Error("token presented without a proof of its dpop key", "jti", payload.ID)
+			return myerrors.ErrTokenBindingMismatch
+		}
+	}
+
+	return nil
+}
+
+// narrowScopes returns the requested scopes that are present in allowed,
+// without duplicates and in the order they were requested.
+func narrowScopes(requested, allowed []string) []string {
+	allowedSet := make(map[string]struct{}, len(allowed))
+	for _, scope := range allowed {
+		allowedSet[scope] = struct{}{}
+	}
+
+	var narrowed []string
+	for _, scope := range requested {
+		if _, ok := allowedSet[scope]; !ok {
+			continue
+		}
+		narrowed = append(narrowed, scope)
+		delete(allowedSet, scope)
+	}
+
+	return narrowed
+}
+
+func (uc *Usecase) hashToken(ctx context.Context, token string) string {
+	_, span := uc.tracer.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer span.End()
+
+	start := time.Now()
+	defer func() { uc.m.ObserveHash(metrics.HashOperationGenerate, time.Since(start)) }()
+
 	hashed := sha256.Sum256([]byte(token))
 	hashedToken, _ := bcrypt.GenerateFromPassword(hashed[:], bcrypt.DefaultCost)
 
 	return string(hashedToken)
 }
 
-func (uc *Usecase) sendEmail() {
+func (uc *Usecase) sendEmail(ctx context.Context) {
+	_, span := uc.tracer.Start(ctx, "smtp.DialAndSend", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
 	email := gomail.NewMessage()
 	email.SetHeader("From", "example@example.com")
 	email.SetHeader("To", "example_user@example.com")
 	email.SetHeader("Subject", "Предупреждение!")
 	email.SetBody("text/html", fmt.Sprintf("Ваш ip адресс сменился"))
 	d := gomail.NewDialer("smtp", 465, "example@example.com", "password")
-	_ = d.DialAndSend(email)
-	uc.log.Info("email sent")
+	tracing.RecordError(span, d.DialAndSend(email))
+	uc.logger(ctx).Info("email sent")
 }
@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/mock/gomock"
+	"refresh/internal/models"
+	mock_auth "refresh/internal/pkg/auth/mocks"
+	"refresh/internal/pkg/metrics"
+	"refresh/internal/pkg/mtls"
+	"refresh/internal/pkg/tokenizer"
+	"refresh/pkg/logger"
+	"refresh/pkg/myerrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUsecase struct {
+	*Usecase
+	repo     *mock_auth.MockRepository
+	denylist *mock_auth.MockDenylist
+	// events holds the recorded audit events.
+	events *[]*models.AuditEvent
+}
+
+func newTestUsecase(t *testing.T) *testUsecase {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	m, err := metrics.New(metrics.Params{Logger: logger.SetupLogger()})
+	require.NoError(t, err)
+	auditor := mock_auth.NewMockAuditor(ctrl)
+	var events []*models.AuditEvent
+	auditor.EXPECT().Record(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, event *models.AuditEvent) { events = append(events, event) }).
+		AnyTimes()
+
+	repo := mock_auth.NewMockRepository(ctrl)
+	denylist := mock_auth.NewMockDenylist(ctrl)
+	uc := New(Params{
+		Repo:     repo,
+		Denylist: denylist,
+		Auditor:  auditor,
+		Tokenizer: tokenizer.New(tokenizer.Params{
+			Config: tokenizer.Config{
+				AccessExpirationTime:  time.Minute,
+				RefreshExpirationTime: time.Hour,
+				KeyJWT:                []byte("test-secret-0123456789abcdef0123456789"),
+			},
+			TracerProvider: noop.NewTracerProvider(),
+			Logger:         logger.SetupLogger(),
+		}),
+		Metrics:        m,
+		TracerProvider: noop.NewTracerProvider(),
+		Logger:         logger.SetupLogger(),
+	})
+	return &testUsecase{Usecase: uc, repo: repo, denylist: denylist, events: &events}
+}
+
+func TestUsecase_Revoke(t *testing.T) {
+	uc := newTestUsecase(t)
+	userID := uuid.New()
+	pair, err := uc.t.GeneratePairToken(context.Background(), &models.TokenPayload{UserID: userID, UserIP: "127.0.0.1"})
+	require.NoError(t, err)
+	legacy, err := uc.t.GenerateJWT(&models.TokenPayload{ID: uuid.New(), UserID: userID, Exp: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+
+	notRevoked := func(times int) {
+		uc.denylist.EXPECT().Contains(gomock.Any(), gomock.Any()).Return(false, nil).Times(times)
+	}
+	endsSession := func() {
+		uc.denylist.EXPECT().Add(gomock.Any(), gomock.Not(pair.SessionID), gomock.Any()).Return(nil)
+		uc.denylist.EXPECT().Add(gomock.Any(), pair.SessionID, gomock.Any()).Return(nil)
+		uc.repo.EXPECT().DeleteSession(gomock.Any(), userID, pair.SessionID).Return(nil)
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		hint        string
+		setupMocks  func()
+		expectedErr error
+	}{
+		{
+			name:  "Current refresh token ends the session",
+			token: pair.RefreshToken,
+			hint:  models.TokenTypeHintRefresh,
+			setupMocks: func() {
+				notRevoked(2)
+				uc.repo.EXPECT().CheckToken(gomock.Any(), userID, gomock.Any()).Return(nil)
+				endsSession()
+			},
+		},
+		{
+			name:  "Stale refresh token is rejected before denylisting",
+			token: pair.RefreshToken,
+			hint:  models.TokenTypeHintRefresh,
+			setupMocks: func() {
+				notRevoked(2)
+				uc.repo.EXPECT().CheckToken(gomock.Any(), userID, gomock.Any()).
+					Return(myerrors.ErrInappropriateRefreshToken)
+			},
+			expectedErr: myerrors.ErrInappropriateRefreshToken,
+		},
+		{
+			name:  "Access token ends its session",
+			token: pair.AccessToken,
+			hint:  models.TokenTypeHintAccess,
+			setupMocks: func() {
+				notRevoked(2)
+				endsSession()
+			},
+		},
+		{
+			name:  "Refresh token with the access hint is still checked",
+			token: pair.RefreshToken,
+			hint:  models.TokenTypeHintAccess,
+			setupMocks: func() {
+				notRevoked(2)
+				uc.repo.EXPECT().CheckToken(gomock.Any(), userID, gomock.Any()).
+					Return(myerrors.ErrInappropriateRefreshToken)
+			},
+			expectedErr: myerrors.ErrInappropriateRefreshToken,
+		},
+		{
+			name:  "Untyped token without hint",
+			token: legacy,
+			setupMocks: func() {
+				notRevoked(1)
+				uc.repo.EXPECT().CheckToken(gomock.Any(), userID, gomock.Any()).
+					Return(myerrors.ErrInappropriateRefreshToken)
+				uc.denylist.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:  "Token of a revoked session cannot be replayed",
+			token: pair.AccessToken,
+			setupMocks: func() {
+				uc.denylist.EXPECT().Contains(gomock.Any(), gomock.Not(pair.SessionID)).Return(false, nil)
+				uc.denylist.EXPECT().Contains(gomock.Any(), pair.SessionID).Return(true, nil)
+			},
+			expectedErr: myerrors.ErrTokenRevoked,
+		},
+		{
+			name:  "Token without session is revoked on its own",
+			token: legacy,
+			hint:  models.TokenTypeHintAccess,
+			setupMocks: func() {
+				notRevoked(1)
+				uc.denylist.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+			err := uc.Revoke(context.Background(), tt.token, tt.hint)
+			assert.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestUsecase_RevokeSessions(t *testing.T) {
+	uc := newTestUsecase(t)
+	userID, sessionID := uuid.New(), uuid.New()
+
+	uc.repo.EXPECT().ListSessions(gomock.Any(), userID).Return([]models.Session{{SessionID: sessionID, UserID: userID}}, nil)
+	uc.denylist.EXPECT().Add(gomock.Any(), sessionID, gomock.Any()).Return(nil)
+	uc.repo.EXPECT().DeleteSession(gomock.Any(), userID, sessionID).Return(nil)
+
+	assert.NoError(t, uc.RevokeSessions(context.Background(), userID))
+}
+
+func TestUsecase_TokenUse(t *testing.T) {
+	uc := newTestUsecase(t)
+	pair, err := uc.t.GeneratePairToken(context.Background(), &models.TokenPayload{UserID: uuid.New(), UserIP: "127.0.0.1"})
+	require.NoError(t, err)
+
+	t.Run("Refresh token is not an access token", func(t *testing.T) {
+		_, err := uc.Validate(context.Background(), pair.RefreshToken)
+		assert.ErrorIs(t, err, myerrors.ErrInvalidToken)
+	})
+
+	t.Run("Access token cannot refresh", func(t *testing.T) {
+		_, err := uc.Refresh(context.Background(), pair.AccessToken, "127.0.0.1", nil)
+		assert.ErrorIs(t, err, myerrors.ErrInvalidToken)
+	})
+
+	t.Run("Access token validates", func(t *testing.T) {
+		uc.denylist.EXPECT().Contains(gomock.Any(), gomock.Any()).Return(false, nil).Times(2)
+		payload, err := uc.Validate(context.Background(), pair.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, models.TokenUseAccess, payload.Use)
+	})
+}
+
+func TestUsecase_Validate_Binding(t *testing.T) {
+	uc := newTestUsecase(t)
+	pair, err := uc.t.GeneratePairToken(context.Background(), &models.TokenPayload{
+		UserID:         uuid.New(),
+		UserIP:         "127.0.0.1",
+		CertThumbprint: "cert-thumbprint",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		expectedErr error
+	}{
+		{
+			name:        "Presented without a certificate",
+			ctx:         context.Background(),
+			expectedErr: myerrors.ErrTokenBindingMismatch,
+		},
+		{
+			name:        "Presented with another certificate",
+			ctx:         mtls.NewContext(context.Background(), &mtls.Identity{Thumbprint: "other-thumbprint"}),
+			expectedErr: myerrors.ErrTokenBindingMismatch,
+		},
+		{
+			name: "Presented with its certificate",
+			ctx:  mtls.NewContext(context.Background(), &mtls.Identity{Thumbprint: "cert-thumbprint"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc.denylist.EXPECT().Contains(gomock.Any(), gomock.Any()).Return(false, nil).Times(2)
+			_, err := uc.Validate(tt.ctx, pair.AccessToken)
+			assert.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestUsecase_IssueAccessToken(t *testing.T) {
+	uc := newTestUsecase(t)
+	userID := uuid.New()
+
+	uc.repo.EXPECT().GetPermissions(gomock.Any(), userID).
+		Return(&models.Permissions{Roles: []string{"admin"}, Scopes: []string{"audit:read", "profile"}}, nil)
+
+	pair, err := uc.IssueAccessToken(context.Background(), &models.TokenPayload{UserID: userID, Scopes: []string{"profile"}})
+	require.NoError(t, err)
+	assert.Empty(t, pair.RefreshToken)
+
+	payload, err := uc.t.ValidateJWT(context.Background(), pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, models.TokenUseAccess, payload.Use)
+	assert.Equal(t, uuid.Nil, payload.SessionID)
+	assert.Equal(t, []string{"profile"}, payload.Scopes)
+
+	require.Len(t, *uc.events, 1)
+	assert.Equal(t, models.AuditEventTokenIssued, (*uc.events)[0].Type)
+}
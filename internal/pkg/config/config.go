@@ -1,53 +1,174 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	_ "github.com/joho/godotenv/autoload"
 	"go.uber.org/fx"
-	"log"
+	"gopkg.in/yaml.v3"
+	"io"
 	"os"
+	"refresh/internal/pkg/audit"
+	grpcDelivery "refresh/internal/pkg/auth/delivery/grpc"
+	httpDelivery "refresh/internal/pkg/auth/delivery/http"
+	"refresh/internal/pkg/cors"
+	"refresh/internal/pkg/csrf"
 	"refresh/internal/pkg/db"
+	"refresh/internal/pkg/denylist"
+	"refresh/internal/pkg/health"
+	"refresh/internal/pkg/secrets"
 	"refresh/internal/pkg/server"
 	"refresh/internal/pkg/tokenizer"
+	"refresh/internal/pkg/tracing"
+	"refresh/migrations"
+	"refresh/pkg/logger"
 )
 
 type Config struct {
-	ConfigPath string `env:"CONFIG_PATH" env-default:"config/config.yaml"`
+	ConfigPath string `yaml:"-" env:"CONFIG_PATH" env-default:"config/config.yaml"`
 
-	HTTPServer server.Config    `yaml:"httpServer"`
-	DB         db.Config        `yaml:"db"`
-	Token      tokenizer.Config `yaml:"tokenizer"`
+	HTTPServer server.Config               `yaml:"httpServer"`
+	GRPCServer grpcDelivery.Config         `yaml:"grpcServer"`
+	DB         db.Config                   `yaml:"db"`
+	Migrations migrations.Config           `yaml:"migrations"`
+	Token      tokenizer.Config            `yaml:"token"`
+	Denylist   denylist.Config             `yaml:"denylist"`
+	Health     health.Config               `yaml:"health"`
+	Tracing    tracing.Config              `yaml:"tracing"`
+	Audit      audit.Config                `yaml:"audit"`
+	Log        logger.Config               `yaml:"log"`
+	CORS       cors.Config                 `yaml:"cors"`
+	Cookie     httpDelivery.CookieConfig   `yaml:"refreshCookie"`
+	Delivery   httpDelivery.DeliveryConfig `yaml:"refreshDelivery"`
+	DPoP       httpDelivery.DPoPConfig     `yaml:"dpop"`
+	CSRF       csrf.Config                 `yaml:"csrf"`
+	Reload     ReloadConfig                `yaml:"reload"`
+	Secrets    secrets.Config              `yaml:"secrets"`
 }
 
 type Out struct {
 	fx.Out
 
+	// Loaded is the whole configuration, which the Watcher compares reloads
+	// against.
+	Loaded *Config
+
 	HTTPServer server.Config
+	GRPCServer grpcDelivery.Config
 	DB         db.Config
+	Migrations migrations.Config
 	Token      tokenizer.Config
+	Denylist   denylist.Config
+	Health     health.Config
+	Tracing    tracing.Config
+	Audit      audit.Config
+	Log        logger.Config
+	CORS       cors.Config
+	Cookie     httpDelivery.CookieConfig
+	Delivery   httpDelivery.DeliveryConfig
+	DPoP       httpDelivery.DPoPConfig
+	CSRF       csrf.Config
+}
+
+// Load reads the environment and the YAML file at CONFIG_PATH, rejects keys
+// the config does not know and validates the result. Every problem found is
+// reported in the returned error.
+func Load() (Out, error) {
+	cfg, err := load()
+	if err != nil {
+		return Out{}, err
+	}
+
+	return Out{
+		Loaded:     cfg,
+		HTTPServer: cfg.HTTPServer,
+		GRPCServer: cfg.GRPCServer,
+		DB:         cfg.DB,
+		Migrations: cfg.Migrations,
+		Token:      cfg.Token,
+		Denylist:   cfg.Denylist,
+		Health:     cfg.Health,
+		Tracing:    cfg.Tracing,
+		Audit:      cfg.Audit,
+		Log:        cfg.Log,
+		CORS:       cfg.CORS,
+		Cookie:     cfg.Cookie,
+		Delivery:   cfg.Delivery,
+		DPoP:       cfg.DPoP,
+		CSRF:       cfg.CSRF,
+	}, nil
 }
 
-func MustLoad() Out {
+func load() (*Config, error) {
 	var cfg Config
 
 	if err := cleanenv.ReadEnv(&cfg); err != nil {
-		log.Printf("cannot read .env file: %s", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("read environment: %w", err)
 	}
 
-	if _, err := os.Stat(cfg.ConfigPath); os.IsNotExist(err) {
-		log.Printf("config file does not exist: %s", cfg.ConfigPath)
-		os.Exit(1)
+	if err := checkKnownFields(cfg.ConfigPath); err != nil {
+		return nil, err
 	}
 
 	if err := cleanenv.ReadConfig(cfg.ConfigPath, &cfg); err != nil {
-		log.Printf("cannot read %s: %v", cfg.ConfigPath, err)
-		os.Exit(1)
+		return nil, fmt.Errorf("read %s: %w", cfg.ConfigPath, err)
 	}
 
-	return Out{
-		HTTPServer: cfg.HTTPServer,
-		DB:         cfg.DB,
-		Token:      cfg.Token,
+	if err := cfg.resolveSecrets(context.Background()); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s:\n%w", cfg.ConfigPath, err)
+	}
+
+	return &cfg, nil
+}
+
+// resolveSecrets fills the secret values, which may also come from _FILE
+// files or the configured secret provider instead of plain variables.
+func (c *Config) resolveSecrets(ctx context.Context) error {
+	resolver, err := secrets.New(c.Secrets)
+	if err != nil {
+		return fmt.Errorf("secrets: %w", err)
+	}
+
+	fields := []struct {
+		name string
+		set  func(value string)
+	}{
+		{"JWT_SECRET", func(value string) { c.Token.KeyJWT = []byte(value) }},
+		{"JWT_PREVIOUS_SECRET", func(value string) { c.Token.PreviousKeyJWT = []byte(value) }},
+		{"POSTGRES_PASSWORD", func(value string) { c.DB.Password = value }},
+	}
+	for _, field := range fields {
+		value, ok, err := resolver.Resolve(ctx, field.name)
+		if err != nil {
+			return fmt.Errorf("secret %s: %w", field.name, err)
+		}
+		if ok {
+			field.set(value)
+		}
+	}
+
+	return nil
+}
+
+// checkKnownFields decodes the file strictly so that misspelled or stale
+// keys fail startup instead of silently leaving defaults in place.
+func checkKnownFields(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open config: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err = dec.Decode(&Config{}); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse %s: %w", path, err)
 	}
+	return nil
 }
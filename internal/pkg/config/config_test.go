@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_RepositoryConfig(t *testing.T) {
+	t.Setenv("CONFIG_PATH", filepath.Join("..", "..", "..", "config", "config.yaml"))
+	t.Setenv("JWT_SECRET", testSecret)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, 5*time.Minute, cfg.Token.AccessExpirationTime)
+	assert.Equal(t, 24*time.Hour, cfg.Token.RefreshExpirationTime)
+	assert.True(t, cfg.Cookie.Secure)
+	assert.True(t, cfg.CSRF.Secure)
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Setenv("CONFIG_PATH", writeConfig(t, "log:\n  level: info\n"))
+	t.Setenv("JWT_SECRET", testSecret)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, 15*time.Minute, cfg.Token.AccessExpirationTime)
+	assert.Equal(t, 24*time.Hour, cfg.Token.RefreshExpirationTime)
+}
+
+func TestLoad_InsecureCookies(t *testing.T) {
+	t.Setenv("CONFIG_PATH", writeConfig(t, "refreshCookie:\n  secure: false\n  sameSite: lax\n"+
+		"csrf:\n  secure: false\n"))
+	t.Setenv("JWT_SECRET", testSecret)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.False(t, cfg.Cookie.Secure)
+	assert.False(t, cfg.CSRF.Secure)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		secret   string
+		expected []string
+	}{
+		{
+			name:     "Unknown key",
+			config:   "tokenizer:\n  accessExpirationTime: 5m\n",
+			secret:   testSecret,
+			expected: []string{"field tokenizer not found"},
+		},
+		{
+			name: "Aggregated validation errors",
+			config: "httpServer:\n  address: localhost\n" +
+				"migrations:\n  onStart: later\n" +
+				"token:\n  accessExpirationTime: 1h\n  refreshExpirationTime: 5m\n",
+			secret: "short",
+			expected: []string{
+				"httpServer.address: invalid address",
+				`migrations: onStart must be "up" or "skip", got "later"`,
+				"token: refreshExpirationTime (5m0s) must be longer than accessExpirationTime (1h0m0s)",
+				"token: JWT_SECRET must be at least 32 bytes",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_PATH", writeConfig(t, tt.config))
+			t.Setenv("JWT_SECRET", tt.secret)
+
+			_, err := Load()
+			require.Error(t, err)
+			for _, msg := range tt.expected {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
+
+func TestLoad_SecretsFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	jwtFile := filepath.Join(dir, "jwt_secret")
+	require.NoError(t, os.WriteFile(jwtFile, []byte(testSecret+"\n"), 0o600))
+	passwordFile := filepath.Join(dir, "postgres_password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("p@ss word"), 0o600))
+
+	t.Setenv("CONFIG_PATH", writeConfig(t, "token:\n  accessExpirationTime: 5m\n"))
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_SECRET_FILE", jwtFile)
+	t.Setenv("POSTGRES_PASSWORD_FILE", passwordFile)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte(testSecret), cfg.Token.KeyJWT)
+	assert.Equal(t, "p@ss word", cfg.DB.Password)
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Validate checks every section and reports all problems at once, each
+// prefixed with the YAML section it belongs to.
+func (c *Config) Validate() error {
+	var r report
+
+	r.add("httpServer.address", validateAddress(c.HTTPServer.Address))
+	r.add("httpServer", c.HTTPServer.Validate())
+	r.add("grpcServer.address", validateAddress(c.GRPCServer.Address))
+	r.add("grpcServer", c.GRPCServer.Validate())
+	r.add("migrations", c.Migrations.Validate())
+	r.add("token", c.Token.Validate())
+	r.add("tracing", c.Tracing.Validate())
+	r.add("log", c.Log.Validate())
+	r.add("cors", c.CORS.Validate())
+	r.add("csrf", c.CSRF.Validate())
+	r.add("refreshCookie", c.Cookie.Validate())
+	r.add("refreshDelivery", c.Delivery.Validate())
+	r.add("dpop", c.DPoP.Validate())
+	r.add("secrets", c.Secrets.Validate())
+	if c.Reload.WatchInterval < 0 {
+		r.add("reload", errors.New("watchInterval must not be negative"))
+	}
+
+	if c.HTTPServer.Address != "" && c.HTTPServer.Address == c.GRPCServer.Address {
+		r.add("grpcServer.address", errors.New("must differ from httpServer.address"))
+	}
+
+	return errors.Join(r.errs...)
+}
+
+type report struct {
+	errs []error
+}
+
+// add records err under section, flattening errors joined with errors.Join
+// so that each problem gets its own line.
+func (r *report) add(section string, err error) {
+	if err == nil {
+		return
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			r.add(section, e)
+		}
+		return
+	}
+	r.errs = append(r.errs, fmt.Errorf("%s: %w", section, err))
+}
+
+func validateAddress(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("invalid port in address %q", addr)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"go.uber.org/fx"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	httpDelivery "refresh/internal/pkg/auth/delivery/http"
+	"refresh/internal/pkg/cors"
+	"refresh/internal/pkg/csrf"
+	"refresh/internal/pkg/tokenizer"
+	"refresh/pkg/logger"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type ReloadConfig struct {
+	// WatchInterval is how often the config file is checked for changes.
+	// Zero disables polling; SIGHUP always triggers a reload.
+	WatchInterval time.Duration `yaml:"watchInterval" env-default:"5s"`
+}
+
+type WatcherParams struct {
+	fx.In
+
+	Config    *Config
+	Tokenizer *tokenizer.Tokenizer
+	CORS      *cors.CORS
+	CSRF      *csrf.Protector
+	Auth      *httpDelivery.Handler
+	LogLevel  *slog.LevelVar
+	Logger    *slog.Logger
+	Lifecycle fx.Lifecycle
+}
+
+// Watcher reloads the configuration on SIGHUP or when the file changes. A
+// reload is validated as a whole and then applied to the parts that can
+// change at runtime: token lifetimes and signing key, CORS and CSRF
+// policies, refresh token delivery and the log level. Listeners, pools and
+// the other sections keep their startup values until a restart; changes to
+// them are only logged.
+type Watcher struct {
+	tokenizer *tokenizer.Tokenizer
+	cors      *cors.CORS
+	csrf      *csrf.Protector
+	auth      *httpDelivery.Handler
+	level     *slog.LevelVar
+	log       *slog.Logger
+
+	mu      sync.Mutex
+	current *Config
+	modTime time.Time
+
+	signals chan os.Signal
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewWatcher(p WatcherParams) *Watcher {
+	w := &Watcher{
+		tokenizer: p.Tokenizer,
+		cors:      p.CORS,
+		csrf:      p.CSRF,
+		auth:      p.Auth,
+		level:     p.LogLevel,
+		log:       p.Logger,
+		current:   p.Config,
+		modTime:   modTime(p.Config.ConfigPath),
+	}
+	p.Lifecycle.Append(fx.StartStopHook(w.Start, w.Stop))
+	return w
+}
+
+func (w *Watcher) Start() {
+	w.signals = make(chan os.Signal, 1)
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+	signal.Notify(w.signals, syscall.SIGHUP)
+	go w.run()
+}
+
+func (w *Watcher) Stop() {
+	signal.Stop(w.signals)
+	close(w.stop)
+	<-w.done
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	var tick <-chan time.Time
+	if interval := w.current.Reload.WatchInterval; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.signals:
+			w.reload("signal")
+		case <-tick:
+			if w.fileChanged() {
+				w.reload("file change")
+			}
+		}
+	}
+}
+
+func (w *Watcher) reload(trigger string) {
+	if err := w.Reload(); err != nil {
+		w.log.Error("config reload failed, keeping the current config", "trigger", trigger, "error", err)
+		return
+	}
+	w.log.Info("config reloaded", "trigger", trigger)
+}
+
+func (w *Watcher) fileChanged() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !modTime(w.current.ConfigPath).Equal(w.modTime)
+}
+
+// Reload reads and validates the configuration and applies its reloadable
+// sections. Nothing is applied, and the current config is kept, when any
+// section of the new configuration is invalid.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Record the file version first so an invalid file is reported once
+	// rather than on every poll.
+	w.modTime = modTime(w.current.ConfigPath)
+
+	next, err := load()
+	if err != nil {
+		return err
+	}
+
+	// load validated the whole config; the setters below run the same
+	// checks again, so once these pass every section applies.
+	if err = errors.Join(
+		next.CORS.Validate(),
+		next.CSRF.Validate(),
+		next.Delivery.Validate(),
+		logger.SetLevel(new(slog.LevelVar), next.Log.Level),
+	); err != nil {
+		return err
+	}
+
+	if sections := restartRequired(w.current, next); len(sections) > 0 {
+		w.log.Warn("config changes that need a restart are ignored", "sections", sections)
+	}
+	// The running service keeps the startup values of the fixed sections,
+	// so later reloads still compare against them.
+	keepFixed(w.current, next)
+
+	if !bytes.Equal(w.current.Token.KeyJWT, next.Token.KeyJWT) {
+		w.log.Info("jwt signing key rotated")
+	}
+
+	if err = errors.Join(
+		w.cors.SetConfig(next.CORS),
+		w.csrf.SetConfig(next.CSRF),
+		w.auth.SetDelivery(next.Delivery),
+		logger.SetLevel(w.level, next.Log.Level),
+	); err != nil {
+		return err
+	}
+	w.tokenizer.SetConfig(next.Token)
+	w.current = next
+	return nil
+}
+
+type section struct {
+	name string
+	// value points to the section within its Config.
+	value any
+}
+
+// fixedSections returns the sections of c that are only read at startup.
+func fixedSections(c *Config) []section {
+	return []section{
+		{"httpServer", &c.HTTPServer},
+		{"grpcServer", &c.GRPCServer},
+		{"db", &c.DB},
+		{"migrations", &c.Migrations},
+		{"denylist", &c.Denylist},
+		{"health", &c.Health},
+		{"tracing", &c.Tracing},
+		{"audit", &c.Audit},
+		{"log.format", &c.Log.Format},
+		{"refreshCookie", &c.Cookie},
+		{"dpop", &c.DPoP},
+		{"reload", &c.Reload},
+	}
+}
+
+// restartRequired names the sections that differ between old and next but
+// are only read at startup.
+func restartRequired(old, next *Config) []string {
+	oldSections, nextSections := fixedSections(old), fixedSections(next)
+
+	var sections []string
+	for i, s := range nextSections {
+		if !reflect.DeepEqual(oldSections[i].value, s.value) {
+			sections = append(sections, s.name)
+		}
+	}
+	return sections
+}
+
+// keepFixed copies the fixed sections of old into next.
+func keepFixed(old, next *Config) {
+	oldSections, nextSections := fixedSections(old), fixedSections(next)
+	for i, s := range nextSections {
+		reflect.ValueOf(s.value).Elem().Set(reflect.ValueOf(oldSections[i].value).Elem())
+	}
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
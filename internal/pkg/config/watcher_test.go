@@ -0,0 +1,114 @@
+package config
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/fx/fxtest"
+	"log/slog"
+	"os"
+	"refresh/internal/models"
+	httpDelivery "refresh/internal/pkg/auth/delivery/http"
+	"refresh/internal/pkg/cors"
+	"refresh/internal/pkg/csrf"
+	"refresh/internal/pkg/tokenizer"
+	"refresh/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watcherConfig = `
+token:
+  accessExpirationTime: 5m
+  refreshExpirationTime: 24h
+log:
+  level: info
+reload:
+  watchInterval: 0s
+`
+
+func TestWatcher_Reload(t *testing.T) {
+	path := writeConfig(t, watcherConfig)
+	t.Setenv("CONFIG_PATH", path)
+	t.Setenv("JWT_SECRET", testSecret)
+
+	out, err := Load()
+	require.NoError(t, err)
+
+	log := logger.SetupLogger()
+	tk := tokenizer.New(tokenizer.Params{Config: out.Token, TracerProvider: noop.NewTracerProvider(), Logger: log})
+	corsPolicy, err := cors.New(out.CORS)
+	require.NoError(t, err)
+	csrfPolicy, err := csrf.New(out.CSRF)
+	require.NoError(t, err)
+	handler, err := httpDelivery.New(httpDelivery.Params{Cookie: out.Cookie, Delivery: out.Delivery, Logger: log})
+	require.NoError(t, err)
+	level := new(slog.LevelVar)
+
+	w := NewWatcher(WatcherParams{
+		Config:    out.Loaded,
+		Tokenizer: tk,
+		CORS:      corsPolicy,
+		CSRF:      csrfPolicy,
+		Auth:      handler,
+		LogLevel:  level,
+		Logger:    log,
+		Lifecycle: fxtest.NewLifecycle(t),
+	})
+
+	accessLifetime := func() time.Duration {
+		pair, err := tk.GeneratePairToken(context.Background(), &models.TokenPayload{UserID: uuid.New()})
+		require.NoError(t, err)
+		return time.Until(pair.ExpAccessToken).Round(time.Minute)
+	}
+	assert.Equal(t, 5*time.Minute, accessLifetime())
+
+	t.Run("Valid change is applied", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(
+			"token:\n  accessExpirationTime: 10m\n  refreshExpirationTime: 24h\nlog:\n  level: debug\n"), 0o600))
+
+		require.NoError(t, w.Reload())
+		assert.Equal(t, 10*time.Minute, accessLifetime())
+		assert.Equal(t, slog.LevelDebug, level.Level())
+	})
+
+	t.Run("Invalid change is rejected", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(
+			"token:\n  accessExpirationTime: 48h\n  refreshExpirationTime: 24h\nlog:\n  level: error\n"), 0o600))
+
+		assert.Error(t, w.Reload())
+		assert.Equal(t, 10*time.Minute, accessLifetime())
+		assert.Equal(t, slog.LevelDebug, level.Level())
+		assert.Equal(t, 10*time.Minute, w.current.Token.AccessExpirationTime)
+	})
+
+	t.Run("Restart-only change is kept pending", func(t *testing.T) {
+		startup := w.current.HTTPServer.Address
+		require.NoError(t, os.WriteFile(path, []byte(
+			"httpServer:\n  address: 0.0.0.0:8081\n"+
+				"token:\n  accessExpirationTime: 10m\n  refreshExpirationTime: 24h\nlog:\n  level: debug\n"), 0o600))
+
+		require.NoError(t, w.Reload())
+		assert.Equal(t, startup, w.current.HTTPServer.Address)
+		assert.Equal(t, []string{"httpServer"}, restartRequired(w.current, mustLoad(t)))
+	})
+}
+
+func mustLoad(t *testing.T) *Config {
+	t.Helper()
+	cfg, err := load()
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestRestartRequired(t *testing.T) {
+	old := &Config{}
+	next := &Config{}
+	next.HTTPServer.Address = "0.0.0.0:8081"
+	next.CORS.AllowedOrigins = []string{"https://app.example.com"}
+
+	assert.Equal(t, []string{"httpServer"}, restartRequired(old, next))
+}
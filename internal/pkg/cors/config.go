@@ -0,0 +1,27 @@
+package cors
+
+import (
+	"errors"
+	"time"
+)
+
+type Config struct {
+	// AllowedOrigins lists origins such as "https://app.example.com". "*"
+	// allows any origin but cannot be combined with AllowCredentials. An
+	// empty list disables CORS.
+	AllowedOrigins   []string      `yaml:"allowedOrigins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowCredentials bool          `yaml:"allowCredentials" env:"CORS_ALLOW_CREDENTIALS"`
+	AllowedMethods   []string      `yaml:"allowedMethods" env-default:"GET,POST"`
+	AllowedHeaders   []string      `yaml:"allowedHeaders" env-default:"Authorization,Content-Type,X-Request-ID,X-Client-ID,X-CSRF-Token,DPoP"`
+	ExposedHeaders   []string      `yaml:"exposedHeaders" env-default:"X-Request-ID"`
+	MaxAge           time.Duration `yaml:"maxAge" env-default:"10m"`
+}
+
+func (c Config) Validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == wildcard && c.AllowCredentials {
+			return errors.New("cors: wildcard origin cannot be combined with credentials")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+// Package cors lets browser clients on other origins call the API.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const wildcard = "*"
+
+type CORS struct {
+	policy atomic.Pointer[policy]
+}
+
+type policy struct {
+	cfg            Config
+	origins        map[string]struct{}
+	anyOrigin      bool
+	methods        string
+	headers        map[string]struct{}
+	allowedHeaders string
+	exposedHeaders string
+	maxAge         string
+}
+
+func New(cfg Config) (*CORS, error) {
+	c := &CORS{}
+	if err := c.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetConfig validates cfg and applies it to the following requests.
+func (c *CORS) SetConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	p := &policy{
+		cfg:            cfg,
+		origins:        make(map[string]struct{}, len(cfg.AllowedOrigins)),
+		methods:        strings.Join(cfg.AllowedMethods, ", "),
+		headers:        make(map[string]struct{}, len(cfg.AllowedHeaders)),
+		allowedHeaders: strings.Join(cfg.AllowedHeaders, ", "),
+		exposedHeaders: strings.Join(cfg.ExposedHeaders, ", "),
+		maxAge:         strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == wildcard {
+			p.anyOrigin = true
+			continue
+		}
+		p.origins[strings.ToLower(origin)] = struct{}{}
+	}
+
+	for _, header := range cfg.AllowedHeaders {
+		p.headers[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+
+	c.policy.Store(p)
+	return nil
+}
+
+// Handler answers preflight requests itself and adds CORS headers to the
+// responses of allowed origins. It wraps the whole router because gorilla/mux
+// rejects OPTIONS requests before route middlewares run. An empty
+// AllowedOrigins list disables it.
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := c.policy.Load()
+		origin := r.Header.Get("Origin")
+		if len(p.cfg.AllowedOrigins) == 0 || origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if !p.originAllowed(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if preflight {
+			p.preflight(w, r, origin)
+			return
+		}
+
+		p.setOrigin(w, origin)
+		if p.exposedHeaders != "" {
+			w.Header().Set("Access-Control-Expose-Headers", p.exposedHeaders)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *policy) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	if !p.methodAllowed(r.Header.Get("Access-Control-Request-Method")) ||
+		!p.headersAllowed(r.Header.Get("Access-Control-Request-Headers")) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	p.setOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", p.methods)
+	if p.allowedHeaders != "" {
+		w.Header().Set("Access-Control-Allow-Headers", p.allowedHeaders)
+	}
+	if p.cfg.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *policy) setOrigin(w http.ResponseWriter, origin string) {
+	if p.anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", wildcard)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if p.cfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (p *policy) originAllowed(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	_, ok := p.origins[strings.ToLower(origin)]
+	return ok
+}
+
+func (p *policy) methodAllowed(method string) bool {
+	for _, allowed := range p.cfg.AllowedMethods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *policy) headersAllowed(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if _, ok := p.headers[http.CanonicalHeaderKey(header)]; !ok {
+			return false
+		}
+	}
+	return true
+}
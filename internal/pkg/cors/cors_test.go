@@ -0,0 +1,113 @@
+package cors
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS_Handler(t *testing.T) {
+	c, err := New(Config{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowCredentials: true,
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		MaxAge:           10 * time.Minute,
+	})
+	require.NoError(t, err)
+
+	handler := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		headers     map[string]string
+		wantCode    int
+		wantOrigin  string
+		wantMaxAge  string
+		wantCreds   string
+		wantExposed string
+	}{
+		{
+			name:     "same origin request",
+			method:   http.MethodGet,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:        "allowed origin",
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://app.example.com"},
+			wantCode:    http.StatusOK,
+			wantOrigin:  "https://app.example.com",
+			wantCreds:   "true",
+			wantExposed: "X-Request-ID",
+		},
+		{
+			name:     "disallowed origin",
+			method:   http.MethodGet,
+			headers:  map[string]string{"Origin": "https://evil.example.com"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "preflight",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "content-type, authorization",
+			},
+			wantCode:   http.StatusNoContent,
+			wantOrigin: "https://app.example.com",
+			wantMaxAge: "600",
+			wantCreds:  "true",
+		},
+		{
+			name:   "preflight with disallowed header",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "X-Custom",
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:   "preflight from disallowed origin",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://evil.example.com",
+				"Access-Control-Request-Method": http.MethodPost,
+			},
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/auth/refresh", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.wantMaxAge, w.Header().Get("Access-Control-Max-Age"))
+			assert.Equal(t, tt.wantCreds, w.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Equal(t, tt.wantExposed, w.Header().Get("Access-Control-Expose-Headers"))
+		})
+	}
+}
+
+func TestNew_WildcardWithCredentials(t *testing.T) {
+	_, err := New(Config{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	assert.Error(t, err)
+}
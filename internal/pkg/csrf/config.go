@@ -0,0 +1,25 @@
+package csrf
+
+import "fmt"
+
+type Config struct {
+	// Mode is "origin", "double-submit" or "none".
+	Mode string `yaml:"mode" env-default:"origin"`
+	// TrustedOrigins may send state-changing requests in addition to the
+	// API's own origin, e.g. "https://app.example.com".
+	TrustedOrigins []string `yaml:"trustedOrigins"`
+	CookieName     string   `yaml:"cookieName" env-default:"csrf_token"`
+	HeaderName     string   `yaml:"headerName" env-default:"X-CSRF-Token"`
+	// Secure marks the double-submit cookie Secure. Like the refresh
+	// cookie's, it is set in the shipped config rather than defaulted.
+	Secure bool `yaml:"secure"`
+}
+
+func (c Config) Validate() error {
+	switch c.Mode {
+	case ModeOrigin, ModeDoubleSubmit, ModeNone:
+		return nil
+	default:
+		return fmt.Errorf("csrf: unknown mode %q", c.Mode)
+	}
+}
@@ -0,0 +1,168 @@
+// Package csrf protects cookie-authenticated endpoints from cross-site
+// request forgery.
+//
+// In origin mode unsafe requests are rejected when their Origin (or Referer)
+// is neither the API's own origin nor trusted. Requests carrying neither
+// header come from non-browser clients and are allowed. In double-submit mode
+// safe requests receive a random token cookie that unsafe requests must echo
+// in a header.
+//
+// Requests carrying an Authorization header are exempt: browsers never attach
+// it on their own, so such a request cannot be forged cross-site.
+package csrf
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"refresh/pkg/myerrors"
+	"refresh/pkg/responser"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	ModeOrigin       = "origin"
+	ModeDoubleSubmit = "double-submit"
+	ModeNone         = "none"
+
+	tokenBytes = 32
+)
+
+type Protector struct {
+	policy atomic.Pointer[policy]
+}
+
+type policy struct {
+	cfg     Config
+	trusted map[string]struct{}
+}
+
+func New(cfg Config) (*Protector, error) {
+	p := &Protector{}
+	if err := p.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetConfig validates cfg and applies it to the following requests.
+func (p *Protector) SetConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	pol := &policy{cfg: cfg, trusted: make(map[string]struct{}, len(cfg.TrustedOrigins))}
+	for _, origin := range cfg.TrustedOrigins {
+		pol.trusted[strings.ToLower(strings.TrimSuffix(origin, "/"))] = struct{}{}
+	}
+
+	p.policy.Store(pol)
+	return nil
+}
+
+func (p *Protector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pol := p.policy.Load()
+		if pol.cfg.Mode == ModeNone {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if isSafe(r.Method) {
+			if pol.cfg.Mode == ModeDoubleSubmit {
+				if err := pol.ensureToken(w, r); err != nil {
+					responser.Send500(w)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var ok bool
+		switch pol.cfg.Mode {
+		case ModeOrigin:
+			ok = pol.checkOrigin(r)
+		case ModeDoubleSubmit:
+			ok = pol.checkToken(r)
+		}
+		if !ok {
+			responser.SendProblem(w, http.StatusForbidden, myerrors.CodeCSRF, "cross-site request rejected")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *policy) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		referer := r.Header.Get("Referer")
+		if referer == "" {
+			return true
+		}
+		u, err := url.Parse(referer)
+		if err != nil {
+			return false
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	_, ok := p.trusted[strings.ToLower(u.Scheme+"://"+u.Host)]
+	return ok
+}
+
+func (p *policy) checkToken(r *http.Request) bool {
+	cookie, err := r.Cookie(p.cfg.CookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(p.cfg.HeaderName)
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) == 1
+}
+
+func (p *policy) ensureToken(w http.ResponseWriter, r *http.Request) error {
+	if cookie, err := r.Cookie(p.cfg.CookieName); err == nil && cookie.Value != "" {
+		return nil
+	}
+
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+
+	// Readable by scripts on purpose: the client copies it into the header.
+	http.SetCookie(w, &http.Cookie{
+		Name:     p.cfg.CookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(buf),
+		Path:     "/",
+		Secure:   p.cfg.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return nil
+}
+
+func isSafe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,94 @@
+package csrf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestProtector_Origin(t *testing.T) {
+	p, err := New(Config{Mode: ModeOrigin, TrustedOrigins: []string{"https://app.example.com"}})
+	require.NoError(t, err)
+	handler := p.Middleware(okHandler)
+
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		wantCode int
+	}{
+		{"safe method cross-site", http.MethodGet, map[string]string{"Origin": "https://evil.example.com"}, http.StatusOK},
+		{"no origin headers", http.MethodPost, nil, http.StatusOK},
+		{"same origin", http.MethodPost, map[string]string{"Origin": "https://auth.example.com"}, http.StatusOK},
+		{"trusted origin", http.MethodPost, map[string]string{"Origin": "https://app.example.com"}, http.StatusOK},
+		{"cross-site origin", http.MethodPost, map[string]string{"Origin": "https://evil.example.com"}, http.StatusForbidden},
+		{"cross-site referer", http.MethodPost, map[string]string{"Referer": "https://evil.example.com/page"}, http.StatusForbidden},
+		{"null origin", http.MethodPost, map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"bearer request", http.MethodPost, map[string]string{"Origin": "https://evil.example.com", "Authorization": "Bearer token"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "https://auth.example.com/api/v1/auth/refresh", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+}
+
+func TestProtector_DoubleSubmit(t *testing.T) {
+	p, err := New(Config{Mode: ModeDoubleSubmit, CookieName: "csrf_token", HeaderName: "X-CSRF-Token"})
+	require.NoError(t, err)
+	handler := p.Middleware(okHandler)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/login", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	token := cookies[0]
+	assert.False(t, token.HttpOnly)
+	assert.NotEmpty(t, token.Value)
+
+	tests := []struct {
+		name     string
+		cookie   bool
+		header   string
+		wantCode int
+	}{
+		{"matching header", true, token.Value, http.StatusOK},
+		{"missing header", true, "", http.StatusForbidden},
+		{"mismatched header", true, "forged", http.StatusForbidden},
+		{"missing cookie", false, token.Value, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+			if tt.cookie {
+				req.AddCookie(token)
+			}
+			if tt.header != "" {
+				req.Header.Set("X-CSRF-Token", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+}
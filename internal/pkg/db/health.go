@@ -0,0 +1,10 @@
+package db
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"refresh/internal/pkg/health"
+)
+
+func NewHealthChecker(pool *pgxpool.Pool) health.Checker {
+	return health.NewChecker("postgres", pool.Ping)
+}
@@ -5,36 +5,43 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 	"log/slog"
+	"net"
+	"net/url"
+	"refresh/internal/pkg/tracing"
+	"strconv"
 )
 
 func getConnStr(cfg *Config) string {
-	return fmt.Sprintf(
-		"postgresql://%s:%s@%s:%d/%s?sslmode=disable",
-		cfg.User,
-		cfg.Password,
-		cfg.Host,
-		cfg.Port,
-		cfg.DB,
-	)
+	u := url.URL{
+		Scheme:   "postgresql",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port))),
+		Path:     cfg.DB,
+		RawQuery: "sslmode=disable",
+	}
+	return u.String()
 }
 
 type PostgresParams struct {
 	fx.In
 
-	Cfg    Config
-	Logger *slog.Logger
+	Cfg            Config
+	TracerProvider trace.TracerProvider
+	Logger         *slog.Logger
 }
 
 func NewPostgresPool(p PostgresParams) (*pgxpool.Pool, error) {
 	connStr := getConnStr(&p.Cfg)
-	p.Logger.Debug(connStr)
+	p.Logger.Debug("connecting to postgres", "host", p.Cfg.Host, "port", p.Cfg.Port, "db", p.Cfg.DB)
 	config, err := pgxpool.ParseConfig(connStr)
 	if err != nil {
 		p.Logger.Error("parse config: " + err.Error())
 		return nil, fmt.Errorf("unable to parse config: %w", err)
 	}
+	config.ConnConfig.Tracer = tracing.NewQueryTracer(p.TracerProvider)
 
 	ctx, cancel := context.WithTimeout(context.Background(), p.Cfg.ConnectTimeout)
 	defer cancel()
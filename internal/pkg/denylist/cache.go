@@ -0,0 +1,78 @@
+package denylist
+
+import (
+	"container/list"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	jti       uuid.UUID
+	revoked   bool
+	expiresAt time.Time
+}
+
+// cache is a fixed-size LRU of denylist lookups. Every entry carries its own
+// expiry: revoked entries live until the token exp, non-revoked ones only for
+// a short time so revocations made by other replicas become visible quickly.
+type cache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[uuid.UUID]*list.Element
+}
+
+func newCache(size int) *cache {
+	return &cache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[uuid.UUID]*list.Element),
+	}
+}
+
+func (c *cache) get(jti uuid.UUID, now time.Time) (revoked bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[jti]
+	if !ok {
+		return false, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if !now.Before(entry.expiresAt) {
+		c.removeElement(el)
+		return false, false
+	}
+
+	c.ll.MoveToFront(el)
+	return entry.revoked, true
+}
+
+func (c *cache) set(jti uuid.UUID, revoked bool, expiresAt time.Time) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[jti]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.revoked = revoked
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[jti] = c.ll.PushFront(&cacheEntry{jti: jti, revoked: revoked, expiresAt: expiresAt})
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *cache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*cacheEntry).jti)
+}
@@ -0,0 +1,53 @@
+package denylist
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Entry expires", func(t *testing.T) {
+		c := newCache(10)
+		jti := uuid.New()
+		c.set(jti, true, now.Add(time.Minute))
+
+		revoked, ok := c.get(jti, now)
+		assert.True(t, ok)
+		assert.True(t, revoked)
+
+		_, ok = c.get(jti, now.Add(time.Minute))
+		assert.False(t, ok)
+	})
+
+	t.Run("Least recently used entry is evicted", func(t *testing.T) {
+		c := newCache(2)
+		first, second, third := uuid.New(), uuid.New(), uuid.New()
+		c.set(first, true, now.Add(time.Hour))
+		c.set(second, false, now.Add(time.Hour))
+		_, _ = c.get(first, now)
+		c.set(third, true, now.Add(time.Hour))
+
+		_, ok := c.get(second, now)
+		assert.False(t, ok)
+		_, ok = c.get(first, now)
+		assert.True(t, ok)
+		_, ok = c.get(third, now)
+		assert.True(t, ok)
+	})
+
+	t.Run("Revocation overrides cached miss", func(t *testing.T) {
+		c := newCache(10)
+		jti := uuid.New()
+		c.set(jti, false, now.Add(time.Second))
+		c.set(jti, true, now.Add(time.Hour))
+
+		revoked, ok := c.get(jti, now.Add(time.Minute))
+		assert.True(t, ok)
+		assert.True(t, revoked)
+	})
+}
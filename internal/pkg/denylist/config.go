@@ -0,0 +1,9 @@
+package denylist
+
+import "time"
+
+type Config struct {
+	CacheSize     int           `yaml:"cacheSize" env-default:"10000"`
+	NegativeTTL   time.Duration `yaml:"negativeTTL" env-default:"5s"`
+	PurgeInterval time.Duration `yaml:"purgeInterval" env-default:"10m"`
+}
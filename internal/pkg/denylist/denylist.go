@@ -0,0 +1,117 @@
+package denylist
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/fx"
+	"log/slog"
+	"time"
+)
+
+const (
+	insertToken  = `INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`
+	checkToken   = `SELECT expires_at FROM revoked_tokens WHERE jti = $1 AND expires_at > now()`
+	purgeExpired = `DELETE FROM revoked_tokens WHERE expires_at <= now()`
+)
+
+type Params struct {
+	fx.In
+
+	Config    Config
+	DB        *pgxpool.Pool
+	Logger    *slog.Logger
+	Lifecycle fx.Lifecycle
+}
+
+// Denylist stores identifiers of revoked tokens until they expire on their
+// own. Lookups go through an in-memory LRU before hitting Postgres.
+type Denylist struct {
+	cfg   Config
+	db    *pgxpool.Pool
+	cache *cache
+	log   *slog.Logger
+	now   func() time.Time
+}
+
+func New(p Params) *Denylist {
+	d := &Denylist{
+		cfg:   p.Config,
+		db:    p.DB,
+		cache: newCache(p.Config.CacheSize),
+		log:   p.Logger,
+		now:   time.Now,
+	}
+
+	if p.Config.PurgeInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		p.Lifecycle.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				go func() {
+					defer close(done)
+					d.purgeLoop(ctx)
+				}()
+				return nil
+			},
+			OnStop: func(stopCtx context.Context) error {
+				cancel()
+				select {
+				case <-done:
+				case <-stopCtx.Done():
+				}
+				return nil
+			},
+		})
+	}
+
+	return d
+}
+
+func (d *Denylist) Add(ctx context.Context, jti uuid.UUID, exp time.Time) error {
+	if _, err := d.db.Exec(ctx, insertToken, jti, exp); err != nil {
+		return err
+	}
+	d.cache.set(jti, true, exp)
+	return nil
+}
+
+func (d *Denylist) Contains(ctx context.Context, jti uuid.UUID) (bool, error) {
+	now := d.now()
+	if revoked, ok := d.cache.get(jti, now); ok {
+		return revoked, nil
+	}
+
+	var expiresAt time.Time
+	if err := d.db.QueryRow(ctx, checkToken, jti).Scan(&expiresAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			d.cache.set(jti, false, now.Add(d.cfg.NegativeTTL))
+			return false, nil
+		}
+		return false, err
+	}
+
+	d.cache.set(jti, true, expiresAt)
+	return true, nil
+}
+
+func (d *Denylist) purgeLoop(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tag, err := d.db.Exec(ctx, purgeExpired)
+			if err != nil {
+				d.log.Error("purge expired revoked tokens", "error", err)
+				continue
+			}
+			d.log.Debug("purged expired revoked tokens", "count", tag.RowsAffected())
+		}
+	}
+}
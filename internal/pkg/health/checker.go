@@ -0,0 +1,39 @@
+package health
+
+import (
+	"context"
+	"go.uber.org/fx"
+)
+
+// CheckersGroup is the fx value group readiness checkers are collected from.
+const CheckersGroup = `group:"health_checkers"`
+
+// Checker reports whether a dependency the service needs to serve traffic
+// is available.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (c checkerFunc) Name() string {
+	return c.name
+}
+
+func (c checkerFunc) Check(ctx context.Context) error {
+	return c.check(ctx)
+}
+
+func NewChecker(name string, check func(ctx context.Context) error) Checker {
+	return checkerFunc{name: name, check: check}
+}
+
+// AsChecker annotates a constructor returning a Checker so that its result
+// joins the readiness checks.
+func AsChecker(constructor any) any {
+	return fx.Annotate(constructor, fx.ResultTags(CheckersGroup))
+}
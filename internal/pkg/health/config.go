@@ -0,0 +1,7 @@
+package health
+
+import "time"
+
+type Config struct {
+	CheckTimeout time.Duration `yaml:"checkTimeout" env-default:"2s"`
+}
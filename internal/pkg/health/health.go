@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"go.uber.org/fx"
+	"log/slog"
+	"net/http"
+	"refresh/pkg/responser"
+	"sync"
+)
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+type Params struct {
+	fx.In
+
+	Config   Config
+	Checkers []Checker `group:"health_checkers"`
+	Logger   *slog.Logger
+}
+
+type Handler struct {
+	cfg      Config
+	checkers []Checker
+	log      *slog.Logger
+}
+
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func New(p Params) *Handler {
+	return &Handler{cfg: p.Config, checkers: p.Checkers, log: p.Logger}
+}
+
+// Liveness reports that the process is running and able to serve requests.
+func (h *Handler) Liveness(w http.ResponseWriter, r *http.Request) {
+	responser.Send200(w, Response{Status: statusOK})
+}
+
+// Readiness runs every registered checker and answers 503 if any fails. The
+// reasons are only logged, the public response names the failed checks.
+func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.CheckTimeout)
+	defer cancel()
+
+	resp := Response{Status: statusOK, Checks: make(map[string]string, len(h.checkers))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, checker := range h.checkers {
+		wg.Add(1)
+		go func(checker Checker) {
+			defer wg.Done()
+
+			result := statusOK
+			if err := checker.Check(ctx); err != nil {
+				h.log.Error("readiness check failed", "check", checker.Name(), "error", err)
+				result = statusFail
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Checks[checker.Name()] = result
+			if result != statusOK {
+				resp.Status = statusFail
+			}
+		}(checker)
+	}
+	wg.Wait()
+
+	if resp.Status != statusOK {
+		responser.Send503(w, resp)
+		return
+	}
+	responser.Send200(w, resp)
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"refresh/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_Readiness(t *testing.T) {
+	ok := NewChecker("postgres", func(context.Context) error { return nil })
+	failing := NewChecker("migrations", func(context.Context) error { return errors.New("version 1 is dirty") })
+
+	tests := []struct {
+		name           string
+		checkers       []Checker
+		expectedCode   int
+		expectedChecks map[string]string
+	}{
+		{
+			name:           "All checks pass",
+			checkers:       []Checker{ok},
+			expectedCode:   http.StatusOK,
+			expectedChecks: map[string]string{"postgres": "ok"},
+		},
+		{
+			name:           "One check fails",
+			checkers:       []Checker{ok, failing},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedChecks: map[string]string{"postgres": "ok", "migrations": "fail"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(Params{
+				Config:   Config{CheckTimeout: time.Second},
+				Checkers: tt.checkers,
+				Logger:   logger.SetupLogger(),
+			})
+			rec := httptest.NewRecorder()
+
+			handler.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			var resp Response
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expectedChecks, resp.Checks)
+		})
+	}
+}
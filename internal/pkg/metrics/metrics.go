@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/fx"
+	"log/slog"
+	"net/http"
+	"refresh/pkg/myerrors"
+	"time"
+)
+
+const namespace = "auth"
+
+const (
+	OperationLogin   = "login"
+	OperationRefresh = "refresh"
+	OperationRevoke  = "revoke"
+
+	HashOperationGenerate = "generate"
+	HashOperationCompare  = "compare"
+)
+
+type Params struct {
+	fx.In
+
+	DB     *pgxpool.Pool
+	Logger *slog.Logger
+}
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	logins          prometheus.Counter
+	refreshes       prometheus.Counter
+	failures        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	hashDuration    *prometheus.HistogramVec
+}
+
+func New(p Params) (*Metrics, error) {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		logins: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Successful logins.",
+		}),
+		refreshes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "refreshes_total",
+			Help:      "Successful token refreshes.",
+		}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "failures_total",
+			Help:      "Failed auth operations by operation and error kind.",
+		}, []string{"operation", "kind"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP handler latency by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+		hashDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "token_hash_duration_seconds",
+			Help:      "Latency of bcrypt hashing and comparison of refresh tokens.",
+			Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+	}
+
+	collectorsToRegister := []prometheus.Collector{
+		m.logins,
+		m.refreshes,
+		m.failures,
+		m.requestDuration,
+		m.hashDuration,
+		newPoolCollector(p.DB),
+		newSessionsCollector(p.DB, p.Logger),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	}
+	for _, c := range collectorsToRegister {
+		if err := m.registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) ObserveLogin(err error) {
+	if err != nil {
+		m.failures.WithLabelValues(OperationLogin, ErrorKind(err)).Inc()
+		return
+	}
+	m.logins.Inc()
+}
+
+func (m *Metrics) ObserveRefresh(err error) {
+	if err != nil {
+		m.failures.WithLabelValues(OperationRefresh, ErrorKind(err)).Inc()
+		return
+	}
+	m.refreshes.Inc()
+}
+
+func (m *Metrics) ObserveRevoke(err error) {
+	if err != nil {
+		m.failures.WithLabelValues(OperationRevoke, ErrorKind(err)).Inc()
+	}
+}
+
+func (m *Metrics) ObserveHash(operation string, d time.Duration) {
+	m.hashDuration.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+// ErrorKind maps an error to a low-cardinality label value, the error code
+// registered in pkg/myerrors.
+func ErrorKind(err error) string {
+	code, _, _ := myerrors.Lookup(err)
+	return code
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"net/http"
+	"net/http/httptest"
+	"refresh/pkg/logger"
+	"refresh/pkg/myerrors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_ObserveRefresh(t *testing.T) {
+	m, err := New(Params{Logger: logger.SetupLogger()})
+	require.NoError(t, err)
+
+	m.ObserveRefresh(nil)
+	m.ObserveRefresh(fmt.Errorf("validate: %w", myerrors.ErrTokenExpired))
+	m.ObserveRefresh(myerrors.ErrInappropriateRefreshToken)
+	m.ObserveRefresh(errors.New("connection refused"))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.refreshes))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.failures.WithLabelValues(OperationRefresh, "token_expired")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.failures.WithLabelValues(OperationRefresh, "inappropriate_refresh_token")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.failures.WithLabelValues(OperationRefresh, myerrors.CodeInternal)))
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	m, err := New(Params{Logger: logger.SetupLogger()})
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.Use(m.Middleware)
+	router.HandleFunc("/api/v1/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	for _, id := range []string{"1", "2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/users/"+id, nil))
+	}
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.requestDuration))
+	assert.Equal(t, uint64(2), histogramCount(t, m, "/api/v1/users/{id}", http.MethodGet, "401"))
+}
+
+func histogramCount(t *testing.T, m *Metrics, labels ...string) uint64 {
+	t.Helper()
+
+	observer, err := m.requestDuration.GetMetricWithLabelValues(labels...)
+	require.NoError(t, err)
+
+	var metric dto.Metric
+	require.NoError(t, observer.(prometheus.Metric).Write(&metric))
+
+	return metric.GetHistogram().GetSampleCount()
+}
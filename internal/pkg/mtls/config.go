@@ -0,0 +1,45 @@
+package mtls
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Config struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	// ClientCAFile enables client certificate verification against the CAs
+	// it contains.
+	ClientCAFile string `yaml:"clientCAFile"`
+	// ClientAuth is "request" to verify certificates when presented, or
+	// "require" to reject clients without one. Ignored without ClientCAFile.
+	ClientAuth string `yaml:"clientAuth" env-default:"request"`
+	// ReloadInterval is how often the files are checked for changes.
+	ReloadInterval time.Duration `yaml:"reloadInterval" env-default:"1m"`
+}
+
+// Validate checks an enabled config; disabled ones are not inspected.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	return c.validate()
+}
+
+func (c Config) validate() error {
+	var errs []error
+	if c.CertFile == "" || c.KeyFile == "" {
+		errs = append(errs, errors.New("tls: certFile and keyFile are required"))
+	}
+	switch c.ClientAuth {
+	case ClientAuthRequest, ClientAuthRequire, "":
+	default:
+		errs = append(errs, fmt.Errorf("tls: unknown clientAuth %q", c.ClientAuth))
+	}
+	if c.ReloadInterval < 0 {
+		errs = append(errs, errors.New("tls: reloadInterval must not be negative"))
+	}
+	return errors.Join(errs...)
+}
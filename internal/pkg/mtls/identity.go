@@ -0,0 +1,72 @@
+package mtls
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"net/http"
+)
+
+// Identity describes a verified client certificate.
+type Identity struct {
+	CommonName string
+	DNSNames   []string
+	URIs       []string
+	// Thumbprint is the base64url SHA-256 of the DER certificate, the
+	// x5t#S256 value of RFC 8705.
+	Thumbprint  string
+	Certificate *x509.Certificate
+}
+
+func NewIdentity(cert *x509.Certificate) *Identity {
+	sum := sha256.Sum256(cert.Raw)
+
+	id := &Identity{
+		CommonName:  cert.Subject.CommonName,
+		DNSNames:    cert.DNSNames,
+		Thumbprint:  base64.RawURLEncoding.EncodeToString(sum[:]),
+		Certificate: cert,
+	}
+	for _, uri := range cert.URIs {
+		id.URIs = append(id.URIs, uri.String())
+	}
+	return id
+}
+
+type ctxKey struct{}
+
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the peer identity of a request made with a verified
+// client certificate.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(ctxKey{}).(*Identity)
+	return id, ok
+}
+
+// Middleware stores the verified client certificate identity in the request
+// context. Only certificates with a verified chain are considered.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			r = r.WithContext(NewContext(r.Context(), NewIdentity(r.TLS.VerifiedChains[0][0])))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnaryServerInterceptor is the gRPC counterpart of Middleware.
+func UnaryServerInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			ctx = NewContext(ctx, NewIdentity(info.State.VerifiedChains[0][0]))
+		}
+	}
+	return handler(ctx, req)
+}
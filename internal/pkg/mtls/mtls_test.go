@@ -0,0 +1,149 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"refresh/pkg/logger"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	tpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:              []string{cn},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	signer, signerKey := tpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certPath, keyPath string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+	if keyPath == "" {
+		return
+	}
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+func TestReloader_ServesAndReloads(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+		ClientAuth:   ClientAuthRequire,
+	}
+
+	ca := newTestCert(t, "test-ca", nil, true)
+	ca.write(t, cfg.ClientCAFile, "")
+	server := newTestCert(t, "server-1", ca, false)
+	server.write(t, cfg.CertFile, cfg.KeyFile)
+	client := newTestCert(t, "billing-service", ca, false)
+
+	reloader, err := NewReloader(cfg, logger.SetupLogger())
+	require.NoError(t, err)
+
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", reloader.TLSConfig())
+	require.NoError(t, err)
+
+	srv := &http.Server{Handler: Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := FromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, id.CommonName)
+	}))}
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{client.tlsCertificate()},
+	}}}
+
+	get := func() (string, string) {
+		resp, err := httpClient.Get("https://" + lis.Addr().String())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		httpClient.CloseIdleConnections()
+		return string(body), resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	peer, serverCN := get()
+	assert.Equal(t, "billing-service", peer)
+	assert.Equal(t, "server-1", serverCN)
+
+	rotated := newTestCert(t, "server-2", ca, false)
+	rotated.write(t, cfg.CertFile, cfg.KeyFile)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(cfg.CertFile, future, future))
+	reloader.Reload()
+
+	_, serverCN = get()
+	assert.Equal(t, "server-2", serverCN)
+}
+
+func TestNewIdentity_Thumbprint(t *testing.T) {
+	cert := newTestCert(t, "svc", nil, true)
+
+	id := NewIdentity(cert.cert)
+
+	assert.Equal(t, "svc", id.CommonName)
+	assert.Len(t, id.Thumbprint, 43)
+}
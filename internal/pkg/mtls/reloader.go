@@ -0,0 +1,181 @@
+// Package mtls serves TLS from certificate files that are reloaded when they
+// change, optionally verifies client certificates and exposes the verified
+// peer identity to handlers.
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+
+	defaultReloadInterval = time.Minute
+)
+
+type material struct {
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  [3]time.Time
+}
+
+// Reloader holds the current certificate and client CA pool and swaps them
+// when the files change on disk. A failed reload keeps the previous material.
+type Reloader struct {
+	cfg        Config
+	clientAuth tls.ClientAuthType
+	current    atomic.Pointer[material]
+	log        *slog.Logger
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+func NewReloader(cfg Config, log *slog.Logger) (*Reloader, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = defaultReloadInterval
+	}
+
+	r := &Reloader{cfg: cfg, clientAuth: tls.NoClientCert, log: log}
+	if cfg.ClientCAFile != "" {
+		switch cfg.ClientAuth {
+		case ClientAuthRequest, "":
+			r.clientAuth = tls.VerifyClientCertIfGiven
+		case ClientAuthRequire:
+			r.clientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	m, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.current.Store(m)
+
+	return r, nil
+}
+
+// TLSConfig returns a server config that always uses the latest material.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			m := r.current.Load()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*m.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    m.clientCAs,
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}
+}
+
+// Start polls the files every ReloadInterval until Stop is called.
+func (r *Reloader) Start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.cfg.ReloadInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.Reload()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (r *Reloader) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
+
+// Reload loads the files again if any of them changed since the last load.
+func (r *Reloader) Reload() {
+	modTimes, err := r.modTimes()
+	if err != nil {
+		r.log.Error("stat tls files", "error", err)
+		return
+	}
+	if modTimes == r.current.Load().modTimes {
+		return
+	}
+
+	m, err := r.load()
+	if err != nil {
+		r.log.Error("reload tls certificate", "error", err)
+		return
+	}
+	r.current.Store(m)
+	r.log.Info("tls certificate reloaded", "not_after", m.cert.Leaf.NotAfter)
+}
+
+func (r *Reloader) load() (*material, error) {
+	modTimes, err := r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: load key pair: %w", err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("tls: parse certificate: %w", err)
+		}
+	}
+
+	m := &material{cert: &cert, modTimes: modTimes}
+
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: read client ca: %w", err)
+		}
+		m.clientCAs = x509.NewCertPool()
+		if !m.clientCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("tls: no certificates in client ca file")
+		}
+	}
+
+	return m, nil
+}
+
+func (r *Reloader) modTimes() ([3]time.Time, error) {
+	var modTimes [3]time.Time
+	for i, path := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
@@ -0,0 +1,58 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	ProviderNone  = "none"
+	ProviderVault = "vault"
+)
+
+type Config struct {
+	// Provider is "none" or "vault". Secrets found in the environment or in
+	// _FILE files take precedence over the provider.
+	Provider string      `yaml:"provider" env:"SECRETS_PROVIDER" env-default:"none"`
+	Vault    VaultConfig `yaml:"vault"`
+}
+
+// VaultConfig points at one entry of a HashiCorp Vault KV secrets engine
+// whose keys are the secret names, e.g. JWT_SECRET.
+type VaultConfig struct {
+	Address string `yaml:"address" env:"VAULT_ADDR"`
+	// Token is read from VAULT_TOKEN or the file named by VAULT_TOKEN_FILE,
+	// never from the config file.
+	Token string `yaml:"-" env:"VAULT_TOKEN"`
+	Mount string `yaml:"mount" env-default:"secret"`
+	Path  string `yaml:"path" env:"VAULT_SECRET_PATH"`
+	// KVVersion is the version of the KV engine mounted at Mount, 1 or 2.
+	KVVersion int           `yaml:"kvVersion" env-default:"2"`
+	Timeout   time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
+func (c Config) Validate() error {
+	switch c.Provider {
+	case ProviderNone, "":
+		return nil
+	case ProviderVault:
+		return c.Vault.Validate()
+	default:
+		return fmt.Errorf("unknown secrets provider %q", c.Provider)
+	}
+}
+
+func (c VaultConfig) Validate() error {
+	var errs []error
+	if c.Address == "" {
+		errs = append(errs, errors.New("vault: address is required"))
+	}
+	if c.Path == "" {
+		errs = append(errs, errors.New("vault: path is required"))
+	}
+	if c.KVVersion != 1 && c.KVVersion != 2 {
+		errs = append(errs, fmt.Errorf("vault: unsupported kvVersion %d", c.KVVersion))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+// Package secrets resolves secret configuration values from the
+// environment, from files mounted by Docker or Kubernetes, or from an
+// external secret store.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FileSuffix marks variables holding the path of a file with the secret,
+// e.g. JWT_SECRET_FILE=/run/secrets/jwt_secret.
+const FileSuffix = "_FILE"
+
+// Provider fetches secrets from an external store.
+type Provider interface {
+	// Secret returns the secret stored under name. ok is false when the
+	// store holds no such secret.
+	Secret(ctx context.Context, name string) (value string, ok bool, err error)
+}
+
+// Resolver looks secrets up in, in order: the file named by NAME_FILE, the
+// NAME environment variable and the configured provider.
+type Resolver struct {
+	provider Provider
+}
+
+func New(cfg Config) (*Resolver, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := &Resolver{}
+	if cfg.Provider == ProviderVault {
+		vault, err := NewVault(cfg.Vault)
+		if err != nil {
+			return nil, err
+		}
+		r.provider = vault
+	}
+	return r, nil
+}
+
+func (r *Resolver) Resolve(ctx context.Context, name string) (string, bool, error) {
+	value, ok, err := FromEnv(name)
+	if err != nil || ok || r.provider == nil {
+		return value, ok, err
+	}
+	return r.provider.Secret(ctx, name)
+}
+
+// FromEnv returns the contents of the file named by NAME_FILE, or else the
+// NAME environment variable. A trailing newline in the file is dropped.
+func FromEnv(name string) (string, bool, error) {
+	if path, ok := os.LookupEnv(name + FileSuffix); ok && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("read %s%s: %w", name, FileSuffix, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", false, nil
+	}
+	return value, true, nil
+}
@@ -0,0 +1,138 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func vaultStub(t *testing.T, path string, body interface{}) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(vaultTokenHeader) != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(vaultResponse{Errors: []string{"permission denied"}})
+			return
+		}
+		if r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(vaultResponse{})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	kv2 := map[string]interface{}{
+		"data": map[string]interface{}{
+			"data": map[string]interface{}{"JWT_SECRET": "from-vault", "PORT": 5432},
+		},
+	}
+	srv := vaultStub(t, "/v1/secret/data/auth", kv2)
+
+	secretFile := filepath.Join(t.TempDir(), "jwt_secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0o600))
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		secret      string
+		expected    string
+		expectedOK  bool
+		expectedErr bool
+	}{
+		{
+			name:       "Provider",
+			secret:     "JWT_SECRET",
+			expected:   "from-vault",
+			expectedOK: true,
+		},
+		{
+			name:       "Environment wins over provider",
+			env:        map[string]string{"JWT_SECRET": "from-env"},
+			secret:     "JWT_SECRET",
+			expected:   "from-env",
+			expectedOK: true,
+		},
+		{
+			name:       "File wins over environment",
+			env:        map[string]string{"JWT_SECRET": "from-env", "JWT_SECRET_FILE": secretFile},
+			secret:     "JWT_SECRET",
+			expected:   "from-file",
+			expectedOK: true,
+		},
+		{
+			name:        "Missing file",
+			env:         map[string]string{"JWT_SECRET_FILE": filepath.Join(t.TempDir(), "missing")},
+			secret:      "JWT_SECRET",
+			expectedErr: true,
+		},
+		{
+			name:   "Unknown secret",
+			secret: "POSTGRES_PASSWORD",
+		},
+		{
+			name:        "Not a string",
+			secret:      "PORT",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VAULT_TOKEN", "vault-token")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			r, err := New(Config{
+				Provider: ProviderVault,
+				Vault:    VaultConfig{Address: srv.URL, Mount: "secret", Path: "auth", KVVersion: 2},
+			})
+			require.NoError(t, err)
+
+			value, ok, err := r.Resolve(context.Background(), tt.secret)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestVault_KVVersion1AndErrors(t *testing.T) {
+	srv := vaultStub(t, "/v1/kv/auth", map[string]interface{}{
+		"data": map[string]interface{}{"POSTGRES_PASSWORD": "p@ss/word"},
+	})
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("vault-token\n"), 0o600))
+	t.Setenv("VAULT_TOKEN_FILE", tokenFile)
+
+	v, err := NewVault(VaultConfig{Address: srv.URL, Mount: "kv", Path: "auth", KVVersion: 1})
+	require.NoError(t, err)
+	value, ok, err := v.Secret(context.Background(), "POSTGRES_PASSWORD")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "p@ss/word", value)
+
+	t.Setenv("VAULT_TOKEN_FILE", "")
+	t.Setenv("VAULT_TOKEN", "wrong-token")
+	v, err = NewVault(VaultConfig{Address: srv.URL, Mount: "kv", Path: "auth", KVVersion: 1})
+	require.NoError(t, err)
+	_, _, err = v.Secret(context.Background(), "POSTGRES_PASSWORD")
+	assert.ErrorContains(t, err, "permission denied")
+}
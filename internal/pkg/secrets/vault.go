@@ -0,0 +1,125 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	vaultTokenEnv    = "VAULT_TOKEN"
+	vaultTokenHeader = "X-Vault-Token"
+)
+
+// Vault reads secrets from one entry of a KV secrets engine. The entry is
+// fetched on first use and cached for the lifetime of the Vault, so a config
+// load makes a single request.
+type Vault struct {
+	cfg    VaultConfig
+	url    string
+	client *http.Client
+
+	once sync.Once
+	data map[string]interface{}
+	err  error
+}
+
+func NewVault(cfg VaultConfig) (*Vault, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if token, ok, err := FromEnv(vaultTokenEnv); err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	} else if ok {
+		cfg.Token = token
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("vault: %s or %s%s is required", vaultTokenEnv, vaultTokenEnv, FileSuffix)
+	}
+
+	base, err := url.Parse(cfg.Address)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("vault: invalid address %q", cfg.Address)
+	}
+
+	path := strings.Trim(cfg.Mount, "/") + "/" + strings.Trim(cfg.Path, "/")
+	if cfg.KVVersion == 2 {
+		path = strings.Trim(cfg.Mount, "/") + "/data/" + strings.Trim(cfg.Path, "/")
+	}
+
+	return &Vault{
+		cfg:    cfg,
+		url:    base.JoinPath("v1", path).String(),
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (v *Vault) Secret(ctx context.Context, name string) (string, bool, error) {
+	v.once.Do(func() { v.data, v.err = v.fetch(ctx) })
+	if v.err != nil {
+		return "", false, v.err
+	}
+
+	raw, ok := v.data[name]
+	if !ok {
+		return "", false, nil
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return "", false, fmt.Errorf("vault: secret %s is not a string", name)
+	}
+	return value, true, nil
+}
+
+type vaultResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []string        `json:"errors"`
+}
+
+func (v *Vault) fetch(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(vaultTokenHeader, v.cfg.Token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body vaultResponse
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("vault: decode response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("vault: unexpected status %d: %s", resp.StatusCode, strings.Join(body.Errors, "; "))
+	}
+
+	data := body.Data
+	if v.cfg.KVVersion == 2 {
+		var versioned struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err = json.Unmarshal(body.Data, &versioned); err != nil {
+			return nil, fmt.Errorf("vault: decode response: %w", err)
+		}
+		data = versioned.Data
+	}
+
+	var secrets map[string]interface{}
+	if err = json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("vault: decode response: %w", err)
+	}
+	return secrets, nil
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"refresh/internal/pkg/auth"
+	"refresh/internal/pkg/tokenizer"
+	"refresh/pkg/authmw"
+)
+
+// NewAdminMiddleware authenticates callers of the admin API with access
+// tokens issued by this service. Keys come from the tokenizer, so they
+// follow reloads and rotations, and tokens revoked on their own or with
+// their session are rejected.
+func NewAdminMiddleware(t *tokenizer.Tokenizer, d auth.Denylist) (*authmw.Middleware, error) {
+	return authmw.New(authmw.Config{
+		SecretKeys: t.VerificationKeys,
+		Revoked: func(ctx context.Context, p *authmw.Principal) (bool, error) {
+			for _, id := range []uuid.UUID{p.TokenID, p.SessionID} {
+				if id == uuid.Nil {
+					continue
+				}
+				if revoked, err := d.Contains(ctx, id); err != nil || revoked {
+					return revoked, err
+				}
+			}
+			return false, nil
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"refresh/internal/models"
+	mock_auth "refresh/internal/pkg/auth/mocks"
+	"refresh/internal/pkg/tokenizer"
+	"refresh/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAdminMiddleware_Revoked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	denylist := mock_auth.NewMockDenylist(ctrl)
+
+	tk := tokenizer.New(tokenizer.Params{
+		Config: tokenizer.Config{
+			AccessExpirationTime:  time.Minute,
+			RefreshExpirationTime: time.Hour,
+			KeyJWT:                []byte("test-secret-0123456789abcdef0123456789"),
+		},
+		TracerProvider: noop.NewTracerProvider(),
+		Logger:         logger.SetupLogger(),
+	})
+	pair, err := tk.GeneratePairToken(context.Background(), &models.TokenPayload{UserID: uuid.New(), UserIP: "127.0.0.1"})
+	require.NoError(t, err)
+
+	mw, err := NewAdminMiddleware(tk, denylist)
+	require.NoError(t, err)
+	handler := mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name         string
+		setupMocks   func()
+		expectedCode int
+	}{
+		{
+			name: "Active token",
+			setupMocks: func() {
+				denylist.EXPECT().Contains(gomock.Any(), gomock.Any()).Return(false, nil).Times(2)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "Token of an ended session",
+			setupMocks: func() {
+				denylist.EXPECT().Contains(gomock.Any(), gomock.Not(pair.SessionID)).Return(false, nil)
+				denylist.EXPECT().Contains(gomock.Any(), pair.SessionID).Return(true, nil)
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "Revoked token",
+			setupMocks: func() {
+				denylist.EXPECT().Contains(gomock.Any(), gomock.Any()).Return(true, nil)
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit", nil)
+			req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
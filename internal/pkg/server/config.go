@@ -1,10 +1,32 @@
 package server
 
-import "time"
+import (
+	"errors"
+	"refresh/internal/pkg/mtls"
+	"time"
+)
 
 type Config struct {
-	Address           string        `yaml:"address" env-default:"localhost:8080"`
+	Address string `yaml:"address" env-default:"localhost:8080"`
+	// MetricsAddress serves /metrics apart from the public API, so it can
+	// be bound to an address only the scraper reaches.
+	MetricsAddress string `yaml:"metricsAddress" env-default:"localhost:9100"`
+	// Timeout bounds reading a whole request and writing its response.
 	Timeout           time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout       time.Duration `yaml:"idleTimeout" env-default:"60s"`
 	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env-default:"10s"`
+	// ShutdownDelay is how long the server keeps serving after reporting
+	// not-ready, so load balancers stop routing to it before it drains.
+	ShutdownDelay time.Duration `yaml:"shutdownDelay" env-default:"0s"`
+	DrainTimeout  time.Duration `yaml:"drainTimeout" env-default:"15s"`
+	TLS           mtls.Config   `yaml:"tls"`
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	if c.Timeout < 0 || c.IdleTimeout < 0 || c.ReadHeaderTimeout < 0 || c.ShutdownDelay < 0 || c.DrainTimeout < 0 {
+		errs = append(errs, errors.New("timeouts must not be negative"))
+	}
+	errs = append(errs, c.TLS.Validate())
+	return errors.Join(errs...)
 }
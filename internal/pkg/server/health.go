@@ -0,0 +1,16 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"refresh/internal/pkg/health"
+)
+
+func NewHealthChecker(r *Readiness) health.Checker {
+	return health.NewChecker("http_server", func(context.Context) error {
+		if !r.Ready() {
+			return errors.New("not accepting traffic")
+		}
+		return nil
+	})
+}
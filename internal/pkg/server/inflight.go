@@ -0,0 +1,24 @@
+package server
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// inFlight counts requests currently being served.
+type inFlight struct {
+	count atomic.Int64
+}
+
+func (f *inFlight) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.count.Add(1)
+		defer f.count.Add(-1)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *inFlight) Count() int64 {
+	return f.count.Load()
+}
@@ -0,0 +1,21 @@
+package server
+
+import "sync/atomic"
+
+// Readiness reports whether the server should receive new traffic. It turns
+// ready once the listener is up and not-ready as soon as shutdown starts.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+func (r *Readiness) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+func (r *Readiness) Ready() bool {
+	return r.ready.Load()
+}
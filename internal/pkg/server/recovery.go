@@ -0,0 +1,36 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"refresh/pkg/logger"
+	"refresh/pkg/responser"
+	"runtime/debug"
+)
+
+// recovery turns a handler panic into a 500 problem response instead of a
+// dropped connection. http.ErrAbortHandler is re-raised as net/http expects.
+func recovery(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+
+				logger.FromContext(r.Context(), log).Error("handler panic",
+					"panic", rec,
+					"stack", string(debug.Stack()),
+				)
+				responser.Send500(w)
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
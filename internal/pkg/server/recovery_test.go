@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"refresh/pkg/logger"
+	"refresh/pkg/myerrors"
+	"refresh/pkg/responser"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	handler := recovery(logger.SetupLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var claims map[string]interface{}
+		_ = claims["sub"].(string)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, responser.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var problem responser.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, myerrors.CodeInternal, problem.Code)
+}
+
+func TestRecovery_AbortHandler(t *testing.T) {
+	handler := recovery(logger.SetupLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -2,35 +2,74 @@ package server
 
 import (
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 	"log/slog"
 	"net/http"
+	"refresh/internal/pkg/accesslog"
+	handlerAudit "refresh/internal/pkg/audit/delivery/http"
 	handlerEmployee "refresh/internal/pkg/auth/delivery/http"
+	"refresh/internal/pkg/cors"
+	"refresh/internal/pkg/csrf"
+	"refresh/internal/pkg/health"
+	"refresh/internal/pkg/metrics"
+	"refresh/internal/pkg/tracing"
+	"refresh/pkg/authmw"
 )
 
 type RouterParams struct {
 	fx.In
 
-	Handler *handlerEmployee.Handler
-	Logger  *slog.Logger
+	Handler        *handlerEmployee.Handler
+	AuditHandler   *handlerAudit.Handler
+	Admin          *authmw.Middleware
+	CORS           *cors.CORS
+	CSRF           *csrf.Protector
+	Health         *health.Handler
+	Metrics        *metrics.Metrics
+	TracerProvider trace.TracerProvider
+	Logger         *slog.Logger
 }
 
 type Router struct {
-	handler *mux.Router
+	handler http.Handler
 }
 
 func NewRouter(p RouterParams) *Router {
-	api := mux.NewRouter().PathPrefix("/api").Subrouter()
+	root := mux.NewRouter()
+	root.Use(
+		accesslog.Route,
+		p.Metrics.Middleware,
+		tracing.Middleware(p.TracerProvider),
+		recovery(p.Logger),
+	)
+
+	root.HandleFunc("/healthz", p.Health.Liveness).Methods(http.MethodGet)
+	root.HandleFunc("/readyz", p.Health.Readiness).Methods(http.MethodGet)
+
+	oauth := root.PathPrefix("/oauth").Subrouter()
+	oauth.HandleFunc("/revoke", p.Handler.Revoke).Methods(http.MethodPost)
+
+	api := root.PathPrefix("/api").Subrouter()
 
 	v1 := api.PathPrefix("/v1").Subrouter()
 
 	auth := v1.PathPrefix("/auth").Subrouter()
 
 	auth.HandleFunc("/login", p.Handler.Authenticate).Methods(http.MethodGet)
-	auth.HandleFunc("/refresh", p.Handler.Refresh).Methods(http.MethodGet)
+	auth.Use(p.CSRF.Middleware)
+
+	auth.HandleFunc("/refresh", p.Handler.Refresh).Methods(http.MethodPost)
+	// Logout for cookie clients: the refresh cookie is scoped to /api/v1/auth.
+	auth.HandleFunc("/revoke", p.Handler.Logout).Methods(http.MethodPost)
+
+	admin := v1.PathPrefix("/admin").Subrouter()
+	admin.Use(p.Admin.MuxMiddleware(), authmw.RequireScopes(handlerAudit.ReadScope))
+
+	admin.HandleFunc("/audit", p.AuditHandler.Query).Methods(http.MethodGet)
 
 	router := &Router{
-		handler: api,
+		handler: accesslog.Middleware(p.Logger)(p.CORS.Handler(root)),
 	}
 
 	p.Logger.Info("registered router")
@@ -1,28 +1,124 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"go.uber.org/fx"
+	"log/slog"
+	"net"
 	"net/http"
+	"refresh/internal/pkg/metrics"
+	"refresh/internal/pkg/mtls"
+	"time"
 )
 
 type Params struct {
 	fx.In
 
-	Config Config
-	Router *Router
+	Config     Config
+	Router     *Router
+	Metrics    *metrics.Metrics
+	Readiness  *Readiness
+	Logger     *slog.Logger
+	Lifecycle  fx.Lifecycle
+	Shutdowner fx.Shutdowner
 }
 
-func RunServer(p Params) {
+func RunServer(p Params) error {
+	requests := &inFlight{}
 
 	srv := &http.Server{
 		Addr:              p.Config.Address,
-		Handler:           p.Router.handler,
+		Handler:           requests.middleware(mtls.Middleware(p.Router.handler)),
+		ReadTimeout:       p.Config.Timeout,
+		WriteTimeout:      p.Config.Timeout,
 		ReadHeaderTimeout: p.Config.ReadHeaderTimeout,
 		IdleTimeout:       p.Config.IdleTimeout,
 	}
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			panic(err)
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", p.Metrics.Handler())
+	metricsSrv := &http.Server{
+		Addr:              p.Config.MetricsAddress,
+		Handler:           metricsMux,
+		ReadHeaderTimeout: p.Config.ReadHeaderTimeout,
+		IdleTimeout:       p.Config.IdleTimeout,
+	}
+
+	var certs *mtls.Reloader
+	if p.Config.TLS.Enabled {
+		var err error
+		if certs, err = mtls.NewReloader(p.Config.TLS, p.Logger); err != nil {
+			return err
 		}
-	}()
+		srv.TLSConfig = certs.TLSConfig()
+	}
+
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			metricsLis, err := net.Listen("tcp", metricsSrv.Addr)
+			if err != nil {
+				return fmt.Errorf("listen metrics: %w", err)
+			}
+			lis, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				_ = metricsLis.Close()
+				return fmt.Errorf("listen http: %w", err)
+			}
+
+			go func() {
+				if err := metricsSrv.Serve(metricsLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					p.Logger.Error("serve metrics", "error", err)
+				}
+			}()
+
+			serve := srv.Serve
+			if certs != nil {
+				certs.Start()
+				serve = func(lis net.Listener) error { return srv.ServeTLS(lis, "", "") }
+			}
+
+			go func() {
+				if err := serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					p.Logger.Error("serve http", "error", err)
+					p.Readiness.SetReady(false)
+					_ = p.Shutdowner.Shutdown(fx.ExitCode(1))
+				}
+			}()
+
+			p.Readiness.SetReady(true)
+			p.Logger.Info("http server started", "address", srv.Addr, "metrics_address", metricsSrv.Addr, "tls", certs != nil)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if certs != nil {
+				defer certs.Stop()
+			}
+			// Metrics stay scrapeable while the API drains.
+			defer metricsSrv.Close()
+
+			p.Readiness.SetReady(false)
+			p.Logger.Info("http server is not ready, draining", "in_flight", requests.Count())
+
+			select {
+			case <-time.After(p.Config.ShutdownDelay):
+			case <-ctx.Done():
+			}
+
+			drainCtx, cancel := context.WithTimeout(ctx, p.Config.DrainTimeout)
+			defer cancel()
+
+			if err := srv.Shutdown(drainCtx); err != nil {
+				p.Logger.Error("drain http server", "error", err, "in_flight", requests.Count())
+				return srv.Close()
+			}
+
+			p.Logger.Info("http server stopped")
+			return nil
+		},
+	})
+
+	return nil
 }
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+	"io"
+	"net"
+	"net/http"
+	"refresh/internal/pkg/metrics"
+	"refresh/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testShutdowner struct{}
+
+func (testShutdowner) Shutdown(...fx.ShutdownOption) error { return nil }
+
+func TestRunServer_GracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	router := mux.NewRouter()
+	router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	m, err := metrics.New(metrics.Params{Logger: logger.SetupLogger()})
+	require.NoError(t, err)
+
+	lc := fxtest.NewLifecycle(t)
+	readiness := NewReadiness()
+	err = RunServer(Params{
+		Config:     Config{Address: addr, MetricsAddress: "127.0.0.1:0", DrainTimeout: time.Second},
+		Router:     &Router{handler: router},
+		Metrics:    m,
+		Readiness:  readiness,
+		Logger:     logger.SetupLogger(),
+		Lifecycle:  lc,
+		Shutdowner: testShutdowner{},
+	})
+	require.NoError(t, err)
+
+	lc.RequireStart()
+	assert.True(t, readiness.Ready())
+
+	type result struct {
+		body string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		done <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	require.NoError(t, lc.Stop(context.Background()))
+	assert.False(t, readiness.Ready())
+
+	res := <-done
+	require.NoError(t, res.err)
+	assert.Equal(t, "done", res.body)
+}
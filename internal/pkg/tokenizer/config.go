@@ -1,9 +1,38 @@
 package tokenizer
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MinKeyLength is the shortest accepted JWT secret. HS512 keys shorter than
+// 256 bits can be brute-forced offline from any issued token.
+const MinKeyLength = 32
 
 type Config struct {
-	AccessExpirationTime  time.Duration `yaml:"accessExpirationTime" env-default:"24h"`
+	AccessExpirationTime  time.Duration `yaml:"accessExpirationTime" env-default:"15m"`
 	RefreshExpirationTime time.Duration `yaml:"refreshExpirationTime" env-default:"24h"`
-	KeyJWT                []byte        `env:"JWT_SECRET" env-required:"true"`
+	KeyJWT                []byte        `yaml:"-" env:"JWT_SECRET"`
+	// PreviousKeyJWT keeps tokens signed before the last rotation valid
+	// across restarts. Optional.
+	PreviousKeyJWT []byte `yaml:"-" env:"JWT_PREVIOUS_SECRET"`
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	if c.AccessExpirationTime <= 0 {
+		errs = append(errs, errors.New("accessExpirationTime must be positive"))
+	}
+	if c.RefreshExpirationTime <= c.AccessExpirationTime {
+		errs = append(errs, fmt.Errorf("refreshExpirationTime (%s) must be longer than accessExpirationTime (%s)",
+			c.RefreshExpirationTime, c.AccessExpirationTime))
+	}
+	if len(c.KeyJWT) < MinKeyLength {
+		errs = append(errs, fmt.Errorf("JWT_SECRET must be at least %d bytes", MinKeyLength))
+	}
+	if len(c.PreviousKeyJWT) > 0 && len(c.PreviousKeyJWT) < MinKeyLength {
+		errs = append(errs, fmt.Errorf("JWT_PREVIOUS_SECRET must be at least %d bytes", MinKeyLength))
+	}
+	return errors.Join(errs...)
 }
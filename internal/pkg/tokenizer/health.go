@@ -0,0 +1,16 @@
+package tokenizer
+
+import (
+	"context"
+	"errors"
+	"refresh/internal/pkg/health"
+)
+
+func NewHealthChecker(t *Tokenizer) health.Checker {
+	return health.NewChecker("signing_keys", func(context.Context) error {
+		if len(t.config().KeyJWT) == 0 {
+			return errors.New("signing key is not loaded")
+		}
+		return nil
+	})
+}
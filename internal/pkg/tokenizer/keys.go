@@ -0,0 +1,28 @@
+package tokenizer
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// generatedKeyBytes is the entropy of generated keys, the HS512 block size.
+const generatedKeyBytes = 64
+
+// GenerateKey returns a random signing key encoded for use as JWT_SECRET.
+func GenerateKey() (string, error) {
+	key := make([]byte, generatedKeyBytes)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("generate key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+// KeyID identifies key without revealing it, so that operators can tell
+// which key a replica uses.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
@@ -1,89 +1,190 @@
 package tokenizer
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 	"log/slog"
 	"refresh/internal/models"
+	"refresh/internal/pkg/tracing"
 	"refresh/pkg/myerrors"
+	"strings"
+	"sync"
 	"time"
 )
 
+// Confirmation claim of certificate-bound (RFC 8705 section 3.1) and
+// DPoP-bound (RFC 9449 section 6.1) tokens.
+const (
+	claimConfirmation   = "cnf"
+	confirmationX5TS256 = "x5t#S256"
+	confirmationJKT     = "jkt"
+)
+
 type Params struct {
 	fx.In
 
-	Config Config
-	Logger *slog.Logger
+	Config         Config
+	TracerProvider trace.TracerProvider
+	Logger         *slog.Logger
 }
 
 type Tokenizer struct {
+	mu  sync.RWMutex
 	cfg Config
-	log *slog.Logger
+	// previousKey still verifies tokens signed before the last key change,
+	// so rotating JWT_SECRET does not log every user out. It is
+	// JWT_PREVIOUS_SECRET when configured, else the key replaced by the last
+	// SetConfig.
+	previousKey []byte
+
+	tracer trace.Tracer
+	log    *slog.Logger
 }
 
 func New(p Params) *Tokenizer {
 	return &Tokenizer{
-		cfg: p.Config,
-		log: p.Logger,
+		cfg:         p.Config,
+		previousKey: p.Config.PreviousKeyJWT,
+		tracer:      p.TracerProvider.Tracer("refresh/internal/pkg/tokenizer"),
+		log:         p.Logger,
+	}
+}
+
+// SetConfig replaces the lifetimes and signing key used from now on. Tokens
+// signed with the replaced key stay valid until the next key change.
+func (t *Tokenizer) SetConfig(cfg Config) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case len(cfg.PreviousKeyJWT) > 0:
+		t.previousKey = cfg.PreviousKeyJWT
+	case !bytes.Equal(cfg.KeyJWT, t.cfg.KeyJWT):
+		t.previousKey = t.cfg.KeyJWT
+	}
+	t.cfg = cfg
+}
+
+// RefreshExpirationTime is the longest a token issued now can stay valid.
+func (t *Tokenizer) RefreshExpirationTime() time.Duration {
+	return t.config().RefreshExpirationTime
+}
+
+// VerificationKeys returns the keys tokens are accepted with: the signing
+// key, then the previous one while it is kept.
+func (t *Tokenizer) VerificationKeys() [][]byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	keys := [][]byte{t.cfg.KeyJWT}
+	if t.previousKey != nil {
+		keys = append(keys, t.previousKey)
 	}
+	return keys
+}
+
+func (t *Tokenizer) config() Config {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.cfg
 }
 
 func (t *Tokenizer) GenerateJWT(payload *models.TokenPayload) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
+	claims := jwt.MapClaims{
+		"jti": payload.ID,
 		"sub": payload.UserID,
 		"ip":  payload.UserIP,
 		"exp": payload.Exp.Unix(),
-	})
+	}
+	if payload.SessionID != uuid.Nil {
+		claims["sid"] = payload.SessionID
+	}
+	if payload.Use != "" {
+		claims["typ"] = payload.Use
+	}
+	if len(payload.Scopes) > 0 {
+		claims["scope"] = strings.Join(payload.Scopes, " ")
+	}
+	if len(payload.Roles) > 0 {
+		claims["roles"] = payload.Roles
+	}
+	cnf := map[string]string{}
+	if payload.CertThumbprint != "" {
+		cnf[confirmationX5TS256] = payload.CertThumbprint
+	}
+	if payload.KeyThumbprint != "" {
+		cnf[confirmationJKT] = payload.KeyThumbprint
+	}
+	if len(cnf) > 0 {
+		claims[claimConfirmation] = cnf
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
 
-	return token.SignedString(t.cfg.KeyJWT)
+	return token.SignedString(t.config().KeyJWT)
 }
 
-func (t *Tokenizer) ValidateJWT(tokenString string) (*models.TokenPayload, error) {
-	t.log.Debug(tokenString)
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, myerrors.ErrInvalidToken
-		}
+func (t *Tokenizer) ValidateJWT(ctx context.Context, tokenString string) (*models.TokenPayload, error) {
+	_, span := t.tracer.Start(ctx, "Tokenizer.ValidateJWT")
+	defer span.End()
 
-		return t.cfg.KeyJWT, nil
-	})
+	t.mu.RLock()
+	key, previousKey := t.cfg.KeyJWT, t.previousKey
+	t.mu.RUnlock()
+
+	token, err := parseHMAC(tokenString, key)
+	if err != nil && previousKey != nil && errors.Is(err, jwt.ErrSignatureInvalid) {
+		token, err = parseHMAC(tokenString, previousKey)
+	}
 	if err != nil {
 		t.log.Error("parsing token", "error", err)
+		tracing.RecordError(span, err)
 		return nil, myerrors.ErrInvalidToken
 	}
 
 	payload, err := parseClaims(token)
 	if err != nil {
 		t.log.Error("parsing token claims", "error", err)
+		tracing.RecordError(span, err)
 		return nil, myerrors.ErrInvalidToken
 	}
 
 	if payload.Exp.Before(time.Now()) {
 		t.log.Error("token expired")
+		tracing.RecordError(span, myerrors.ErrTokenExpired)
 		return nil, myerrors.ErrTokenExpired
 	}
 
 	return payload, nil
 }
 
-func (t *Tokenizer) GeneratePairToken(payload *models.TokenPayload) (*models.PairToken, error) {
-	pair := &models.PairToken{}
-	payload.Exp = time.Now().Add(t.cfg.AccessExpirationTime)
-	pair.ExpAccessToken = payload.Exp
-	accessToken, err := t.GenerateJWT(payload)
+func (t *Tokenizer) GeneratePairToken(ctx context.Context, payload *models.TokenPayload) (*models.PairToken, error) {
+	_, span := t.tracer.Start(ctx, "Tokenizer.GeneratePairToken")
+	defer span.End()
+
+	cfg := t.config()
+	if payload.SessionID == uuid.Nil {
+		payload.SessionID = uuid.New()
+	}
+	pair, err := t.generateAccess(cfg, payload)
 	if err != nil {
-		t.log.Error("generating access token", "error", err)
+		tracing.RecordError(span, err)
 		return nil, err
 	}
-	pair.AccessToken = accessToken
 
-	payload.Exp = time.Now().Add(t.cfg.RefreshExpirationTime)
+	payload.ID = uuid.New()
+	payload.Use = models.TokenUseRefresh
+	payload.Exp = time.Now().Add(cfg.RefreshExpirationTime)
 	pair.ExpRefreshToken = payload.Exp
 	refreshToken, err := t.GenerateJWT(payload)
 	if err != nil {
 		t.log.Error("generating refresh token", "error", err)
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 	pair.RefreshToken = refreshToken
@@ -91,17 +192,88 @@ func (t *Tokenizer) GeneratePairToken(payload *models.TokenPayload) (*models.Pai
 	return pair, nil
 }
 
+// GenerateAccessToken issues an access token outside of any session, so
+// there is no refresh token and no sid.
+func (t *Tokenizer) GenerateAccessToken(ctx context.Context, payload *models.TokenPayload) (*models.PairToken, error) {
+	_, span := t.tracer.Start(ctx, "Tokenizer.GenerateAccessToken")
+	defer span.End()
+
+	payload.SessionID = uuid.Nil
+	pair, err := t.generateAccess(t.config(), payload)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (t *Tokenizer) generateAccess(cfg Config, payload *models.TokenPayload) (*models.PairToken, error) {
+	pair := &models.PairToken{TokenType: models.TokenTypeBearer, SessionID: payload.SessionID}
+	if payload.KeyThumbprint != "" {
+		pair.TokenType = models.TokenTypeDPoP
+	}
+	payload.ID = uuid.New()
+	payload.Use = models.TokenUseAccess
+	payload.Exp = time.Now().Add(cfg.AccessExpirationTime)
+	pair.ExpAccessToken = payload.Exp
+	accessToken, err := t.GenerateJWT(payload)
+	if err != nil {
+		t.log.Error("generating access token", "error", err)
+		return nil, err
+	}
+	pair.AccessToken = accessToken
+
+	return pair, nil
+}
+
+func parseHMAC(tokenString string, key []byte) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, myerrors.ErrInvalidToken
+		}
+
+		return key, nil
+	})
+}
+
 func parseClaims(token *jwt.Token) (*models.TokenPayload, error) {
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
 		return nil, myerrors.ErrInvalidToken
 	}
 
-	userID, err := uuid.Parse(claims["sub"].(string))
+	sub, ok := claims["sub"].(string)
+	if !ok {
+		return nil, errors.New("invalid userID in token claims")
+	}
+	userID, err := uuid.Parse(sub)
 	if err != nil {
 		return nil, errors.New("invalid userID in token claims")
 	}
 
+	// Tokens issued before jti was introduced carry no identifier and are
+	// left with uuid.Nil, which is never denylisted.
+	var jti uuid.UUID
+	if rawJTI, ok := claims["jti"].(string); ok {
+		jti, err = uuid.Parse(rawJTI)
+		if err != nil {
+			return nil, errors.New("invalid jti in token claims")
+		}
+	}
+
+	var sid uuid.UUID
+	if rawSID, ok := claims["sid"].(string); ok {
+		sid, err = uuid.Parse(rawSID)
+		if err != nil {
+			return nil, errors.New("invalid sid in token claims")
+		}
+	}
+
+	use, ok := claims["typ"].(string)
+	if _, present := claims["typ"]; present && !ok {
+		return nil, errors.New("invalid typ in token claims")
+	}
+
 	ip, ok := claims["ip"].(string)
 	if !ok {
 		return nil, errors.New("invalid IP in token claims")
@@ -113,9 +285,53 @@ func parseClaims(token *jwt.Token) (*models.TokenPayload, error) {
 	}
 	expTime := time.Unix(int64(exp), 0)
 
+	var scopes []string
+	if rawScope, ok := claims["scope"]; ok {
+		scope, ok := rawScope.(string)
+		if !ok {
+			return nil, errors.New("invalid scope in token claims")
+		}
+		scopes = strings.Fields(scope)
+	}
+
+	var roles []string
+	if rawRoles, ok := claims["roles"]; ok {
+		list, ok := rawRoles.([]interface{})
+		if !ok {
+			return nil, errors.New("invalid roles in token claims")
+		}
+		for _, rawRole := range list {
+			role, ok := rawRole.(string)
+			if !ok {
+				return nil, errors.New("invalid roles in token claims")
+			}
+			roles = append(roles, role)
+		}
+	}
+
+	var certThumbprint, keyThumbprint string
+	if rawCnf, ok := claims[claimConfirmation]; ok {
+		cnf, ok := rawCnf.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid cnf in token claims")
+		}
+		certThumbprint, _ = cnf[confirmationX5TS256].(string)
+		keyThumbprint, _ = cnf[confirmationJKT].(string)
+		if certThumbprint == "" && keyThumbprint == "" {
+			return nil, errors.New("invalid cnf in token claims")
+		}
+	}
+
 	return &models.TokenPayload{
-		UserID: userID,
-		UserIP: ip,
-		Exp:    expTime,
+		ID:             jti,
+		SessionID:      sid,
+		Use:            use,
+		UserID:         userID,
+		UserIP:         ip,
+		Exp:            expTime,
+		Scopes:         scopes,
+		Roles:          roles,
+		CertThumbprint: certThumbprint,
+		KeyThumbprint:  keyThumbprint,
 	}, nil
 }
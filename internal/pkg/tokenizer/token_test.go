@@ -0,0 +1,146 @@
+package tokenizer
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace/noop"
+	"refresh/internal/models"
+	"refresh/pkg/logger"
+	"refresh/pkg/myerrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenizer_GenerateAndValidate(t *testing.T) {
+	tk := &Tokenizer{
+		cfg: Config{
+			AccessExpirationTime:  time.Minute,
+			RefreshExpirationTime: time.Hour,
+			KeyJWT:                []byte("test-secret"),
+		},
+		tracer: noop.NewTracerProvider().Tracer(""),
+		log:    logger.SetupLogger(),
+	}
+
+	tests := []struct {
+		name    string
+		payload *models.TokenPayload
+	}{
+		{
+			name: "With scopes and roles",
+			payload: &models.TokenPayload{
+				UserID: uuid.New(),
+				UserIP: "127.0.0.1",
+				Scopes: []string{"profile:read", "orders:write"},
+				Roles:  []string{"admin"},
+			},
+		},
+		{
+			name: "Certificate bound",
+			payload: &models.TokenPayload{
+				UserID:         uuid.New(),
+				UserIP:         "127.0.0.1",
+				CertThumbprint: "bwcK0esc3ACC3DB2Y5_lESsXE8o9ltc05O89jdN-dg2",
+			},
+		},
+		{
+			name: "DPoP bound",
+			payload: &models.TokenPayload{
+				UserID:        uuid.New(),
+				UserIP:        "127.0.0.1",
+				KeyThumbprint: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+			},
+		},
+		{
+			name: "Without scopes and roles",
+			payload: &models.TokenPayload{
+				UserID: uuid.New(),
+				UserIP: "127.0.0.1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := tk.GeneratePairToken(context.Background(), tt.payload)
+			require.NoError(t, err)
+
+			got, err := tk.ValidateJWT(context.Background(), pair.AccessToken)
+			require.NoError(t, err)
+
+			assert.NotEqual(t, uuid.Nil, got.ID)
+			assert.Equal(t, tt.payload.UserID, got.UserID)
+			assert.Equal(t, tt.payload.UserIP, got.UserIP)
+			assert.Equal(t, tt.payload.Scopes, got.Scopes)
+			assert.Equal(t, tt.payload.Roles, got.Roles)
+			assert.Equal(t, tt.payload.CertThumbprint, got.CertThumbprint)
+			assert.Equal(t, tt.payload.KeyThumbprint, got.KeyThumbprint)
+		})
+	}
+}
+
+func TestTokenizer_ValidateMalformedClaims(t *testing.T) {
+	secret := []byte("test-secret")
+	tk := &Tokenizer{
+		cfg:    Config{KeyJWT: secret},
+		tracer: noop.NewTracerProvider().Tracer(""),
+		log:    logger.SetupLogger(),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
+		"sub": 42,
+		"ip":  "127.0.0.1",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString(secret)
+	require.NoError(t, err)
+
+	assert.NotPanics(t, func() {
+		_, err = tk.ValidateJWT(context.Background(), token)
+	})
+	assert.Error(t, err)
+}
+
+func TestTokenizer_SetConfigKeepsPreviousKey(t *testing.T) {
+	tk := &Tokenizer{
+		cfg: Config{
+			AccessExpirationTime:  time.Minute,
+			RefreshExpirationTime: time.Hour,
+			KeyJWT:                []byte("first-secret"),
+		},
+		tracer: noop.NewTracerProvider().Tracer(""),
+		log:    logger.SetupLogger(),
+	}
+
+	first, err := tk.GeneratePairToken(context.Background(), &models.TokenPayload{UserID: uuid.New(), UserIP: "127.0.0.1"})
+	require.NoError(t, err)
+
+	tk.SetConfig(Config{AccessExpirationTime: time.Minute, RefreshExpirationTime: time.Hour, KeyJWT: []byte("second-secret")})
+	_, err = tk.ValidateJWT(context.Background(), first.AccessToken)
+	assert.NoError(t, err)
+
+	tk.SetConfig(Config{AccessExpirationTime: time.Minute, RefreshExpirationTime: time.Hour, KeyJWT: []byte("third-secret")})
+	_, err = tk.ValidateJWT(context.Background(), first.AccessToken)
+	assert.ErrorIs(t, err, myerrors.ErrInvalidToken)
+}
+
+func TestTokenizer_ConfiguredPreviousKey(t *testing.T) {
+	old, err := GenerateKey()
+	require.NoError(t, err)
+	current, err := GenerateKey()
+	require.NoError(t, err)
+
+	cfg := Config{AccessExpirationTime: time.Minute, RefreshExpirationTime: time.Hour, KeyJWT: []byte(old)}
+	signer := New(Params{Config: cfg, TracerProvider: noop.NewTracerProvider(), Logger: logger.SetupLogger()})
+	pair, err := signer.GeneratePairToken(context.Background(), &models.TokenPayload{UserID: uuid.New()})
+	require.NoError(t, err)
+
+	cfg.KeyJWT, cfg.PreviousKeyJWT = []byte(current), []byte(old)
+	verifier := New(Params{Config: cfg, TracerProvider: noop.NewTracerProvider(), Logger: logger.SetupLogger()})
+	_, err = verifier.ValidateJWT(context.Background(), pair.AccessToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, KeyID([]byte(old)), KeyID([]byte(current)))
+}
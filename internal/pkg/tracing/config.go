@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type Config struct {
+	// Exporter is one of none, stdout or otlp.
+	Exporter    string  `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" env-default:"none"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" env-default:"localhost:4317"`
+	Insecure    bool    `yaml:"insecure" env-default:"true"`
+	ServiceName string  `yaml:"serviceName" env:"OTEL_SERVICE_NAME" env-default:"auth"`
+	SampleRatio float64 `yaml:"sampleRatio" env-default:"1"`
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	switch c.Exporter {
+	case ExporterNone, ExporterStdout, ExporterOTLP, "":
+	default:
+		errs = append(errs, fmt.Errorf("unknown trace exporter %q", c.Exporter))
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		errs = append(errs, errors.New("sampleRatio must be between 0 and 1"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// OnStartUp applies pending migrations before the servers start.
+	OnStartUp = "up"
+	// OnStartSkip leaves the schema to a separate migration job. The
+	// service still starts, but reports not-ready until the schema is
+	// current.
+	OnStartSkip = "skip"
+)
+
+type Config struct {
+	OnStart string `yaml:"onStart" env:"MIGRATIONS_ON_START" env-default:"up"`
+	// LockTimeout bounds the wait for another replica or job migrating
+	// the same database.
+	LockTimeout time.Duration `yaml:"lockTimeout" env-default:"5m"`
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	switch c.OnStart {
+	case OnStartUp, OnStartSkip:
+	default:
+		errs = append(errs, fmt.Errorf("onStart must be %q or %q, got %q", OnStartUp, OnStartSkip, c.OnStart))
+	}
+	if c.LockTimeout <= 0 {
+		errs = append(errs, errors.New("lockTimeout must be positive"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"io/fs"
+	"refresh/internal/pkg/health"
+)
+
+const selectVersion = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+// NewHealthChecker reports not-ready until the schema has reached the latest
+// embedded migration and is not left dirty by a failed run. A newer schema,
+// migrated by the next release during a rolling deploy, is accepted.
+func NewHealthChecker(pool *pgxpool.Pool) (health.Checker, error) {
+	latest, err := latestVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	return health.NewChecker("migrations", func(ctx context.Context) error {
+		var (
+			version int64
+			dirty   bool
+		)
+		if err := pool.QueryRow(ctx, selectVersion).Scan(&version, &dirty); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.New("no migrations applied")
+			}
+			return err
+		}
+
+		switch {
+		case dirty:
+			return fmt.Errorf("version %d is dirty", version)
+		case uint(version) < latest:
+			return fmt.Errorf("version %d applied, %d expected", version, latest)
+		}
+		return nil
+	}), nil
+}
+
+func latestVersion() (uint, error) {
+	sourceDriver, err := iofs.New(migrationFiles, "postgres")
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize migrations source driver: %w", err)
+	}
+	defer sourceDriver.Close()
+
+	version, err := sourceDriver.First()
+	if err != nil {
+		return 0, fmt.Errorf("read first migration: %w", err)
+	}
+
+	for {
+		next, err := sourceDriver.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read migration after %d: %w", version, err)
+		}
+		version = next
+	}
+}
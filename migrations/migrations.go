@@ -1,19 +1,31 @@
 package migrations
 
 import (
+	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"go.uber.org/fx"
 	"log/slog"
+	"time"
 )
 
+// lockKey names the advisory lock that serialises migrations across
+// replicas and migration jobs.
+const lockKey int64 = 0x7265667265736800 // "refresh\x00"
+
+const lockRetryInterval = time.Second
+
+var ErrLockTimeout = errors.New("timed out waiting for another instance to finish migrating")
+
 type Params struct {
 	fx.In
 
+	Config Config
 	DB     *sql.DB
 	Logger *slog.Logger
 }
@@ -21,38 +33,206 @@ type Params struct {
 //go:embed postgres/*.sql
 var migrationFiles embed.FS
 
-func RunMigrations(p Params) error {
+// Migrator applies the embedded migrations to the database. Changes to the
+// schema hold a Postgres advisory lock, so replicas starting together
+// migrate one at a time and the later ones find nothing left to do.
+type Migrator struct {
+	m      *migrate.Migrate
+	db     *sql.DB
+	cfg    Config
+	latest uint
+	log    *slog.Logger
+}
+
+// Status is the schema version recorded in the database. Version is zero
+// when no migration has been applied.
+type Status struct {
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"`
+	Latest  uint `json:"latest"`
+}
+
+func New(p Params) (*Migrator, error) {
+	latest, err := latestVersion()
+	if err != nil {
+		return nil, err
+	}
+
 	sourceDriver, err := iofs.New(migrationFiles, "postgres")
 	if err != nil {
-		return fmt.Errorf("failed to initialize migrations source driver: %w", err)
+		return nil, fmt.Errorf("failed to initialize migrations source driver: %w", err)
 	}
 
 	dbDriver, err := postgres.WithInstance(p.DB, &postgres.Config{})
 	if err != nil {
-		return fmt.Errorf("failed to initialize postgres driver: %w", err)
+		return nil, fmt.Errorf("failed to initialize postgres driver: %w", err)
 	}
 
 	m, err := migrate.NewWithInstance("iofs", sourceDriver, "postgres", dbDriver)
 	if err != nil {
-		return fmt.Errorf("failed to initialize migrations: %w", err)
+		return nil, fmt.Errorf("failed to initialize migrations: %w", err)
 	}
 
-	if err = m.Up(); err != nil && err != migrate.ErrNoChange {
-		p.Logger.Error("failed to run migrations: ", "myerrors", err)
-		return fmt.Errorf("migration up failed: %w", err)
+	return &Migrator{m: m, db: p.DB, cfg: p.Config, latest: latest, log: p.Logger}, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func() error {
+		err := m.m.Up()
+		if dirty := (migrate.ErrDirty{}); errors.As(err, &dirty) {
+			return fmt.Errorf("migration up failed: %w; repair the schema and force the version", err)
+		}
+		if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("migration up failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// Down rolls back the last steps migrations, or all of them when steps is
+// not positive.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func() error {
+		var err error
+		if steps > 0 {
+			err = m.m.Steps(-steps)
+		} else {
+			err = m.m.Down()
+		}
+		if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("migration down failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// Goto migrates up or down to version.
+func (m *Migrator) Goto(ctx context.Context, version uint) error {
+	return m.withLock(ctx, func() error {
+		if err := m.m.Migrate(version); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("migration to version %d failed: %w", version, err)
+		}
+		return nil
+	})
+}
+
+// Force records version as applied and clears the dirty flag without
+// running any migration, after a failed migration was repaired by hand.
+// A version of -1 records that no migration is applied.
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	if version < -1 || version > int(m.latest) {
+		return fmt.Errorf("force version %d: must be between -1 and %d", version, m.latest)
 	}
+	return m.withLock(ctx, func() error {
+		if err := m.m.Force(version); err != nil {
+			return fmt.Errorf("force version %d: %w", version, err)
+		}
+		return nil
+	})
+}
+
+// Version returns the applied schema version, which is zero when no
+// migration has been applied, and whether the last migration failed.
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, dirty, nil
+}
 
-	err = sourceDriver.Close()
+func (m *Migrator) Status() (Status, error) {
+	version, dirty, err := m.Version()
 	if err != nil {
-		p.Logger.Error("failed to close migrations sourceDriver", "myerrors", err)
+		return Status{}, err
+	}
+	return Status{Version: version, Dirty: dirty, Latest: m.latest}, nil
+}
+
+// Close releases the migration drivers, including the *sql.DB they use.
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.m.Close()
+	if err := errors.Join(sourceErr, dbErr); err != nil {
+		m.log.Error("failed to close migrations drivers", "error", err)
 		return err
 	}
+	return nil
+}
+
+// withLock runs fn holding the migration lock. The lock is polled rather
+// than waited for, so that giving up after LockTimeout leaves no pending
+// lock request behind that could be granted later.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	lockCtx, cancel := context.WithTimeout(ctx, m.cfg.LockTimeout)
+	defer cancel()
+
+	for waiting := false; ; waiting = true {
+		var locked bool
+		if err = conn.QueryRowContext(lockCtx, `SELECT pg_try_advisory_lock($1)`, lockKey).Scan(&locked); err != nil {
+			if lockCtx.Err() != nil && ctx.Err() == nil {
+				return ErrLockTimeout
+			}
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		if locked {
+			break
+		}
+		if !waiting {
+			m.log.Info("waiting for another instance to finish migrating", "timeout", m.cfg.LockTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-lockCtx.Done():
+			return ErrLockTimeout
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			m.log.Error("failed to release migration lock", "error", err)
+		}
+	}()
+
+	return fn()
+}
 
-	err = dbDriver.Close()
+// RunMigrations gates startup on the schema: it runs while the application
+// is built, before any lifecycle hook starts a server. With onStart set to
+// skip it only logs how far the schema is behind.
+func RunMigrations(p Params) error {
+	m, err := New(p)
 	if err != nil {
-		p.Logger.Error("failed to close migrations dbDriver", "myerrors", err)
 		return err
 	}
 
-	return nil
+	if p.Config.OnStart == OnStartSkip {
+		status, err := m.Status()
+		if err != nil {
+			_ = m.Close()
+			return err
+		}
+		if status.Dirty || status.Version < status.Latest {
+			p.Logger.Warn("migrations are left to a separate job, the service stays not-ready until the schema is current",
+				"version", status.Version, "latest", status.Latest, "dirty", status.Dirty)
+		}
+		return m.Close()
+	}
+
+	if err = m.Up(context.Background()); err != nil {
+		p.Logger.Error("failed to run migrations", "error", err)
+		_ = m.Close()
+		return err
+	}
+
+	return m.Close()
 }
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS role_scopes;
+DROP TABLE IF EXISTS user_roles;
\ No newline at end of file
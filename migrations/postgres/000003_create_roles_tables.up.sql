@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL,
+    role TEXT NOT NULL,
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE TABLE IF NOT EXISTS role_scopes (
+    role TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    PRIMARY KEY (role, scope)
+);
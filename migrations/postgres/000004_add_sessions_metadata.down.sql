@@ -0,0 +1,3 @@
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS user_ip;
\ No newline at end of file
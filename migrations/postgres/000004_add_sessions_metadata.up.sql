@@ -0,0 +1,3 @@
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS user_ip TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1,2 @@
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS session_id UUID NOT NULL DEFAULT uuid_generate_v4();
@@ -0,0 +1,401 @@
+// Package authmw verifies access tokens issued by the auth service.
+//
+// Downstream services wrap their handlers with Middleware.Handler (or register
+// it on a gorilla/mux router with Use), then read the caller from the request
+// context with FromContext. Failures are answered with pkg/responser problems.
+package authmw
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"refresh/pkg/dpop"
+	"refresh/pkg/myerrors"
+	"refresh/pkg/responser"
+	"strings"
+	"time"
+)
+
+// tokenUseAccess is the typ claim of access tokens. Refresh tokens carry
+// the same claims otherwise and must not be accepted as bearer tokens.
+const tokenUseAccess = "access"
+
+const (
+	defaultJWKSRefreshInterval    = time.Hour
+	defaultJWKSMinRefreshInterval = time.Minute
+)
+
+var (
+	ErrMissingToken      = errors.New("access token not found")
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrTokenRevoked      = errors.New("token revoked")
+	ErrInsufficientScope = errors.New("insufficient scope")
+	ErrCertificateBound  = errors.New("token is bound to a different certificate")
+	ErrKeyBound          = errors.New("token is bound to a different dpop key")
+)
+
+// Config selects how tokens are verified. Exactly one of Secret, SecretKeys
+// and JWKSURL must be set: Secret or SecretKeys for HMAC-signed tokens,
+// JWKSURL for asymmetric keys.
+type Config struct {
+	Secret []byte
+	// SecretKeys returns the HMAC keys tokens may be signed with, tried in
+	// order, for issuers that rotate their key while the service runs.
+	SecretKeys func() [][]byte
+
+	JWKSURL                string
+	JWKSRefreshInterval    time.Duration
+	JWKSMinRefreshInterval time.Duration
+	HTTPClient             *http.Client
+
+	// Revoked reports whether a verified token was revoked since it was
+	// issued, for issuers that keep a denylist. A failing check rejects
+	// the token.
+	Revoked func(ctx context.Context, p *Principal) (bool, error)
+
+	// RequiredScopes are enforced on every request passing through Handler.
+	RequiredScopes []string
+
+	// ClientCertificate returns the certificate the request was made with,
+	// for certificate-bound tokens (RFC 8705). It defaults to the TLS peer
+	// certificate; services behind a TLS-terminating proxy read it from the
+	// header their proxy forwards.
+	ClientCertificate func(r *http.Request) *x509.Certificate
+
+	// DPoP configures the verification of proofs sent with DPoP-bound
+	// tokens (RFC 9449).
+	DPoP dpop.Config
+}
+
+type Middleware struct {
+	cfg     Config
+	secrets func() [][]byte
+	keys    *keySet
+	proofs  *dpop.Verifier
+	methods []string
+}
+
+func New(cfg Config) (*Middleware, error) {
+	if cfg.ClientCertificate == nil {
+		cfg.ClientCertificate = peerCertificate
+	}
+	proofs, err := dpop.NewVerifier(cfg.DPoP)
+	if err != nil {
+		return nil, err
+	}
+	m := &Middleware{cfg: cfg, proofs: proofs}
+
+	switch {
+	case (len(cfg.Secret) > 0 || cfg.SecretKeys != nil) && cfg.JWKSURL != "",
+		len(cfg.Secret) > 0 && cfg.SecretKeys != nil:
+		return nil, errors.New("authmw: secret, secret keys and jwks url are mutually exclusive")
+	case len(cfg.Secret) > 0:
+		m.secrets = func() [][]byte { return [][]byte{cfg.Secret} }
+		m.methods = []string{"HS256", "HS384", "HS512"}
+	case cfg.SecretKeys != nil:
+		m.secrets = cfg.SecretKeys
+		m.methods = []string{"HS256", "HS384", "HS512"}
+	case cfg.JWKSURL != "":
+		if cfg.JWKSRefreshInterval <= 0 {
+			cfg.JWKSRefreshInterval = defaultJWKSRefreshInterval
+		}
+		if cfg.JWKSMinRefreshInterval <= 0 {
+			cfg.JWKSMinRefreshInterval = defaultJWKSMinRefreshInterval
+		}
+		client := cfg.HTTPClient
+		if client == nil {
+			client = http.DefaultClient
+		}
+		m.keys = newKeySet(cfg.JWKSURL, client, cfg.JWKSRefreshInterval, cfg.JWKSMinRefreshInterval)
+		m.methods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+	default:
+		return nil, errors.New("authmw: either secret or jwks url is required")
+	}
+
+	return m, nil
+}
+
+// Handler authenticates the request and enforces Config.RequiredScopes.
+// It can be passed directly to mux.Router.Use.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, ok := authorization(r)
+		if !ok {
+			unauthorized(w, ErrMissingToken)
+			return
+		}
+
+		principal, err := m.Verify(r.Context(), token)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+
+		if err = VerifyBinding(principal, m.cfg.ClientCertificate(r)); err != nil {
+			unauthorized(w, err)
+			return
+		}
+
+		if err = m.verifyProof(r, scheme, token, principal); err != nil {
+			unauthorized(w, err)
+			return
+		}
+
+		if !hasScopes(principal, m.cfg.RequiredScopes) {
+			forbidden(w)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), principal)))
+	})
+}
+
+// MuxMiddleware returns Handler typed for gorilla/mux.
+func (m *Middleware) MuxMiddleware() mux.MiddlewareFunc {
+	return m.Handler
+}
+
+// RequireScopes returns a middleware that rejects principals missing any of
+// the given scopes. It must run after Handler.
+func RequireScopes(scopes ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := FromContext(r.Context())
+			if !ok {
+				unauthorized(w, ErrMissingToken)
+				return
+			}
+			if !hasScopes(principal, scopes) {
+				forbidden(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Verify checks the token signature, expiry, type and, with Config.Revoked,
+// revocation and returns its principal.
+func (m *Middleware) Verify(ctx context.Context, tokenString string) (*Principal, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods(m.methods))
+
+	var (
+		c   claims
+		err error
+	)
+	if m.keys != nil {
+		_, err = parser.ParseWithClaims(tokenString, &c, func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return m.keys.key(ctx, kid)
+		})
+	} else {
+		err = jwt.ErrSignatureInvalid
+		for _, secret := range m.secrets() {
+			c = claims{}
+			_, err = parser.ParseWithClaims(tokenString, &c, func(*jwt.Token) (interface{}, error) {
+				return secret, nil
+			})
+			if !errors.Is(err, jwt.ErrSignatureInvalid) {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	p, err := c.principal()
+	if err != nil || m.cfg.Revoked == nil {
+		return p, err
+	}
+
+	revoked, err := m.cfg.Revoked(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: check revocation: %v", ErrInvalidToken, err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return p, nil
+}
+
+// VerifyBinding checks that a certificate-bound principal was presented with
+// the certificate it is bound to. Unbound principals always pass. Handler
+// calls it; gRPC services call it with the peer certificate.
+func VerifyBinding(p *Principal, cert *x509.Certificate) error {
+	if p.CertThumbprint == "" {
+		return nil
+	}
+	if cert == nil {
+		return ErrCertificateBound
+	}
+
+	sum := sha256.Sum256(cert.Raw)
+	thumbprint := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(thumbprint), []byte(p.CertThumbprint)) != 1 {
+		return ErrCertificateBound
+	}
+	return nil
+}
+
+// verifyProof enforces RFC 9449 section 7: DPoP-bound tokens come with the
+// DPoP scheme and a proof signed by their key over the request and the
+// token hash, and the DPoP scheme is only used with such tokens.
+func (m *Middleware) verifyProof(r *http.Request, scheme, token string, p *Principal) error {
+	isDPoP := strings.EqualFold(scheme, dpop.Scheme)
+	if p.KeyThumbprint == "" {
+		if isDPoP {
+			return fmt.Errorf("%w: token is not dpop-bound", ErrInvalidToken)
+		}
+		return nil
+	}
+	if !isDPoP {
+		return ErrKeyBound
+	}
+
+	proof, err := m.proofs.VerifyRequest(r, token)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(proof.Thumbprint), []byte(p.KeyThumbprint)) != 1 {
+		return ErrKeyBound
+	}
+	return nil
+}
+
+func peerCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Use   string        `json:"typ"`
+	SID   string        `json:"sid"`
+	IP    string        `json:"ip"`
+	Scope string        `json:"scope"`
+	Roles []string      `json:"roles"`
+	Cnf   *confirmation `json:"cnf,omitempty"`
+}
+
+type confirmation struct {
+	X5TS256 string `json:"x5t#S256"`
+	JKT     string `json:"jkt"`
+}
+
+func (c *claims) principal() (*Principal, error) {
+	if c.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: exp claim is missing", ErrInvalidToken)
+	}
+	if c.Use != tokenUseAccess {
+		return nil, fmt.Errorf("%w: not an access token", ErrInvalidToken)
+	}
+
+	userID, err := uuid.Parse(c.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid sub claim", ErrInvalidToken)
+	}
+
+	var tokenID uuid.UUID
+	if c.ID != "" {
+		tokenID, err = uuid.Parse(c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid jti claim", ErrInvalidToken)
+		}
+	}
+
+	var sessionID uuid.UUID
+	if c.SID != "" {
+		sessionID, err = uuid.Parse(c.SID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid sid claim", ErrInvalidToken)
+		}
+	}
+
+	p := &Principal{
+		TokenID:   tokenID,
+		SessionID: sessionID,
+		UserID:    userID,
+		IP:        c.IP,
+		Scopes:    strings.Fields(c.Scope),
+		Roles:     c.Roles,
+		ExpiresAt: c.ExpiresAt.Time,
+	}
+	if c.Cnf != nil {
+		if c.Cnf.X5TS256 == "" && c.Cnf.JKT == "" {
+			return nil, fmt.Errorf("%w: invalid cnf claim", ErrInvalidToken)
+		}
+		p.CertThumbprint = c.Cnf.X5TS256
+		p.KeyThumbprint = c.Cnf.JKT
+	}
+
+	return p, nil
+}
+
+// authorization returns the token of a Bearer or DPoP Authorization header.
+func authorization(r *http.Request) (scheme, token string, ok bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok = strings.Cut(header, " ")
+	if !ok || token == "" || (!strings.EqualFold(scheme, "Bearer") && !strings.EqualFold(scheme, dpop.Scheme)) {
+		return "", "", false
+	}
+	return scheme, strings.TrimSpace(token), true
+}
+
+func hasScopes(p *Principal, scopes []string) bool {
+	for _, scope := range scopes {
+		if !p.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func unauthorized(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrMissingToken) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		responser.SendProblem(w, http.StatusUnauthorized, myerrors.CodeMissingToken, ErrMissingToken.Error())
+		return
+	}
+	if errors.Is(err, ErrKeyBound) {
+		w.Header().Set("WWW-Authenticate", dpopChallenge("invalid_token"))
+		responser.SendProblem(w, http.StatusUnauthorized, myerrors.CodeTokenBindingMismatch, ErrKeyBound.Error())
+		return
+	}
+	if errors.Is(err, dpop.ErrMissingProof) || errors.Is(err, dpop.ErrInvalidProof) {
+		w.Header().Set("WWW-Authenticate", dpopChallenge("invalid_dpop_proof"))
+		responser.SendProblem(w, http.StatusUnauthorized, myerrors.CodeInvalidDPoPProof, dpop.ErrInvalidProof.Error())
+		return
+	}
+	if errors.Is(err, ErrTokenRevoked) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		responser.SendProblem(w, http.StatusUnauthorized, myerrors.CodeTokenRevoked, ErrTokenRevoked.Error())
+		return
+	}
+	if errors.Is(err, ErrCertificateBound) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		responser.SendProblem(w, http.StatusUnauthorized, myerrors.CodeTokenBindingMismatch, ErrCertificateBound.Error())
+		return
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	responser.SendProblem(w, http.StatusUnauthorized, myerrors.CodeInvalidToken, ErrInvalidToken.Error())
+}
+
+func dpopChallenge(code string) string {
+	return fmt.Sprintf(`%s error="%s", algs="%s"`, dpop.Scheme, code, strings.Join(dpop.Algorithms, " "))
+}
+
+func forbidden(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+	responser.SendProblem(w, http.StatusForbidden, myerrors.CodeInsufficientScope, ErrInsufficientScope.Error())
+}
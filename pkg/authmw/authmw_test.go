@@ -0,0 +1,327 @@
+package authmw
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("test-secret")
+
+func signHS512(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString(testSecret)
+	require.NoError(t, err)
+	return token
+}
+
+func TestMiddleware_Handler(t *testing.T) {
+	userID := uuid.New()
+	mw, err := New(Config{Secret: testSecret, RequiredScopes: []string{"orders:read"}})
+	require.NoError(t, err)
+
+	var got *Principal
+	handler := mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name         string
+		header       string
+		expectedCode int
+	}{
+		{
+			name: "Success case",
+			header: "Bearer " + signHS512(t, jwt.MapClaims{
+				"jti":   uuid.NewString(),
+				"sub":   userID.String(),
+				"typ":   "access",
+				"ip":    "127.0.0.1",
+				"exp":   time.Now().Add(time.Minute).Unix(),
+				"scope": "orders:read orders:write",
+				"roles": []string{"admin"},
+			}),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "Refresh token",
+			header: "Bearer " + signHS512(t, jwt.MapClaims{
+				"sub":   userID.String(),
+				"typ":   "refresh",
+				"exp":   time.Now().Add(time.Minute).Unix(),
+				"scope": "orders:read",
+			}),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Missing token",
+			header:       "",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "Expired token",
+			header: "Bearer " + signHS512(t, jwt.MapClaims{
+				"sub":   userID.String(),
+				"typ":   "access",
+				"exp":   time.Now().Add(-time.Minute).Unix(),
+				"scope": "orders:read",
+			}),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "Insufficient scope",
+			header: "Bearer " + signHS512(t, jwt.MapClaims{
+				"sub":   userID.String(),
+				"typ":   "access",
+				"exp":   time.Now().Add(time.Minute).Unix(),
+				"scope": "orders:write",
+			}),
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedCode == http.StatusOK {
+				require.NotNil(t, got)
+				assert.Equal(t, userID, got.UserID)
+				assert.True(t, got.HasRole("admin"))
+			}
+		})
+	}
+}
+
+func TestMiddleware_VerifyJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{{
+			Kty: "RSA",
+			Kid: "key-1",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer srv.Close()
+
+	mw, err := New(Config{JWKSURL: srv.URL})
+	require.NoError(t, err)
+
+	sign := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub": uuid.NewString(),
+			"typ": "access",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	_, err = mw.Verify(context.Background(), sign("key-1"))
+	assert.NoError(t, err)
+
+	_, err = mw.Verify(context.Background(), sign("key-2"))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = mw.Verify(context.Background(), signHS512(t, jwt.MapClaims{
+		"sub": uuid.NewString(),
+		"typ": "access",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestMiddleware_VerifySecretKeys(t *testing.T) {
+	current, previous := []byte("current-secret"), testSecret
+	keys := [][]byte{current, previous}
+	mw, err := New(Config{SecretKeys: func() [][]byte { return keys }})
+	require.NoError(t, err)
+
+	token := signHS512(t, jwt.MapClaims{
+		"sub": uuid.NewString(),
+		"typ": "access",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+
+	_, err = mw.Verify(context.Background(), token)
+	assert.NoError(t, err, "previous key is accepted")
+
+	keys = [][]byte{current}
+	_, err = mw.Verify(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidToken, "dropped key is rejected")
+}
+
+func TestMiddleware_CertificateBoundToken(t *testing.T) {
+	bound := &x509.Certificate{Raw: []byte("bound certificate")}
+	other := &x509.Certificate{Raw: []byte("other certificate")}
+	sum := sha256.Sum256(bound.Raw)
+	thumbprint := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	token := signHS512(t, jwt.MapClaims{
+		"sub": uuid.NewString(),
+		"typ": "access",
+		"exp": time.Now().Add(time.Minute).Unix(),
+		"cnf": map[string]string{"x5t#S256": thumbprint},
+	})
+
+	tests := []struct {
+		name         string
+		cert         *x509.Certificate
+		expectedCode int
+	}{
+		{name: "Bound certificate", cert: bound, expectedCode: http.StatusOK},
+		{name: "Different certificate", cert: other, expectedCode: http.StatusUnauthorized},
+		{name: "No certificate", cert: nil, expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, err := New(Config{
+				Secret:            testSecret,
+				ClientCertificate: func(*http.Request) *x509.Certificate { return tt.cert },
+			})
+			require.NoError(t, err)
+
+			handler := mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				p, _ := FromContext(r.Context())
+				assert.Equal(t, thumbprint, p.CertThumbprint)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestMiddleware_DPoPBoundToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwk := map[string]string{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+	members, err := json.Marshal(struct {
+		Crv string `json:"crv"`
+		Kty string `json:"kty"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}{jwk["crv"], jwk["kty"], jwk["x"], jwk["y"]})
+	require.NoError(t, err)
+	sum := sha256.Sum256(members)
+	jkt := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	token := signHS512(t, jwt.MapClaims{
+		"sub": uuid.NewString(),
+		"typ": "access",
+		"exp": time.Now().Add(time.Minute).Unix(),
+		"cnf": map[string]string{"jkt": jkt},
+	})
+	tokenHash := sha256.Sum256([]byte(token))
+
+	proof := func(ath string) string {
+		p := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+			"jti": uuid.NewString(),
+			"htm": http.MethodGet,
+			"htu": "http://example.com/orders",
+			"iat": time.Now().Unix(),
+			"ath": ath,
+		})
+		p.Header["typ"] = "dpop+jwt"
+		p.Header["jwk"] = jwk
+		signed, err := p.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	tests := []struct {
+		name         string
+		scheme       string
+		proof        string
+		expectedCode int
+	}{
+		{
+			name:         "Valid proof",
+			scheme:       "DPoP",
+			proof:        proof(base64.RawURLEncoding.EncodeToString(tokenHash[:])),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Bearer scheme",
+			scheme:       "Bearer",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Missing proof",
+			scheme:       "DPoP",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Proof for another token",
+			scheme:       "DPoP",
+			proof:        proof("invalid"),
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	mw, err := New(Config{Secret: testSecret})
+	require.NoError(t, err)
+	handler := mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := FromContext(r.Context())
+		assert.Equal(t, jkt, p.KeyThumbprint)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.Header.Set("Authorization", tt.scheme+" "+token)
+			if tt.proof != "" {
+				req.Header.Set("DPoP", tt.proof)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package authmw
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Principal is the authenticated caller extracted from a verified access token.
+type Principal struct {
+	TokenID uuid.UUID
+	// SessionID is the sid of the session the token was issued in, or
+	// uuid.Nil.
+	SessionID uuid.UUID
+	UserID    uuid.UUID
+	IP        string
+	Scopes    []string
+	Roles     []string
+	ExpiresAt time.Time
+	// CertThumbprint is set for certificate-bound tokens (RFC 8705).
+	CertThumbprint string
+	// KeyThumbprint is set for DPoP-bound tokens (RFC 9449).
+	KeyThumbprint string
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored by the middleware, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
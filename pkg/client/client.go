@@ -0,0 +1,373 @@
+// Package client is a Go SDK for the auth API.
+//
+// A Client logs in once, keeps the token pair in memory and refreshes it
+// shortly before the access token expires. Concurrent callers share a single
+// refresh request. Transport wraps an http.RoundTripper so that requests to
+// other services carry the current access token.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+	"io"
+	"net/http"
+	"net/url"
+	"refresh/pkg/responser"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	loginPath   = "/api/v1/auth/login"
+	refreshPath = "/api/v1/auth/refresh"
+	revokePath  = "/oauth/revoke"
+
+	defaultRefreshCookieName = "refresh_token"
+	defaultCSRFHeaderName    = "X-CSRF-Token"
+
+	clientIDHeader = "X-Client-ID"
+
+	defaultRefreshBefore = 30 * time.Second
+)
+
+var ErrNotAuthenticated = errors.New("client is not authenticated")
+
+// APIError is returned when the auth API answers with a non-200 status.
+// Code is the stable error code from the problem body, see pkg/myerrors.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("auth api: %d %s", e.StatusCode, e.Message)
+}
+
+type Config struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// RefreshBefore is how long before access token expiry a refresh is made.
+	RefreshBefore time.Duration
+	// ClientID is sent as X-Client-ID and selects the server's refresh token
+	// delivery mode for this client.
+	ClientID string
+	// RefreshCookieName must match the server's refreshCookie settings,
+	// including a "__Host-" prefix. Defaults to "refresh_token".
+	RefreshCookieName string
+	// CSRFCookieName enables the double-submit CSRF mode: the token the
+	// server sets in this cookie is echoed in CSRFHeaderName on refresh.
+	CSRFCookieName string
+	CSRFHeaderName string
+}
+
+type Tokens struct {
+	AccessToken     string
+	RefreshToken    string
+	AccessExpiresAt time.Time
+}
+
+type Client struct {
+	baseURL       *url.URL
+	httpClient    *http.Client
+	refreshBefore time.Duration
+	clientID      string
+	refreshCookie string
+	csrfCookie    string
+	csrfHeader    string
+	now           func() time.Time
+
+	mu        sync.RWMutex
+	tokens    *Tokens
+	csrfToken string
+
+	refreshGroup singleflight.Group
+}
+
+func New(cfg Config) (*Client, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parse base url: %w", err)
+	}
+
+	c := &Client{
+		baseURL:       baseURL,
+		httpClient:    cfg.HTTPClient,
+		refreshBefore: cfg.RefreshBefore,
+		clientID:      cfg.ClientID,
+		refreshCookie: cfg.RefreshCookieName,
+		csrfCookie:    cfg.CSRFCookieName,
+		csrfHeader:    cfg.CSRFHeaderName,
+		now:           time.Now,
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if c.refreshBefore <= 0 {
+		c.refreshBefore = defaultRefreshBefore
+	}
+	if c.refreshCookie == "" {
+		c.refreshCookie = defaultRefreshCookieName
+	}
+	if c.csrfHeader == "" {
+		c.csrfHeader = defaultCSRFHeaderName
+	}
+
+	return c, nil
+}
+
+// Login authenticates the user and stores the issued token pair.
+func (c *Client) Login(ctx context.Context, userID uuid.UUID, scopes ...string) (*Tokens, error) {
+	query := url.Values{"id": {userID.String()}}
+	if len(scopes) > 0 {
+		query.Set("scope", strings.Join(scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint(loginPath, query), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := c.doTokenRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	c.SetTokens(tokens)
+
+	return tokens, nil
+}
+
+// Refresh rotates the stored token pair unconditionally. Concurrent calls
+// share one request to the auth API.
+func (c *Client) Refresh(ctx context.Context) (*Tokens, error) {
+	return c.refresh(ctx, "")
+}
+
+// AccessToken returns a valid access token, refreshing the pair first when
+// it is about to expire.
+func (c *Client) AccessToken(ctx context.Context) (string, error) {
+	tokens := c.Tokens()
+	if tokens == nil {
+		return "", ErrNotAuthenticated
+	}
+
+	if c.now().Add(c.refreshBefore).Before(tokens.AccessExpiresAt) {
+		return tokens.AccessToken, nil
+	}
+
+	tokens, err := c.refresh(ctx, tokens.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	return tokens.AccessToken, nil
+}
+
+// Revoke revokes the stored refresh token and forgets the pair.
+func (c *Client) Revoke(ctx context.Context) error {
+	tokens := c.Tokens()
+	if tokens == nil {
+		return ErrNotAuthenticated
+	}
+
+	form := url.Values{
+		"token":           {tokens.RefreshToken},
+		"token_type_hint": {"refresh_token"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(revokePath, nil), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return readAPIError(resp)
+	}
+
+	c.SetTokens(nil)
+	return nil
+}
+
+// Tokens returns a copy of the stored token pair, or nil before Login.
+func (c *Client) Tokens() *Tokens {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.tokens == nil {
+		return nil
+	}
+	tokens := *c.tokens
+	return &tokens
+}
+
+// SetTokens replaces the stored token pair, e.g. with one persisted by a CLI.
+func (c *Client) SetTokens(tokens *Tokens) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if tokens == nil {
+		c.tokens = nil
+		return
+	}
+	stored := *tokens
+	c.tokens = &stored
+}
+
+// refresh rotates the pair unless stale is set and the stored access token
+// has already been replaced, which happens when several callers observe the
+// same expired token one after another.
+func (c *Client) refresh(ctx context.Context, stale string) (*Tokens, error) {
+	tokens, err, _ := c.refreshGroup.Do("refresh", func() (interface{}, error) {
+		current := c.Tokens()
+		if current == nil {
+			return nil, ErrNotAuthenticated
+		}
+		if stale != "" && current.AccessToken != stale {
+			return current, nil
+		}
+
+		// The request is shared between callers, so one of them giving up
+		// must not cancel it for the others.
+		req, err := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodPost, c.endpoint(refreshPath, nil), nil)
+		if err != nil {
+			return nil, err
+		}
+		// The token is sent both ways so refresh works in every delivery
+		// mode; the server reads only the sources enabled for this client.
+		req.Header.Set("Authorization", "Bearer "+current.RefreshToken)
+		req.AddCookie(&http.Cookie{Name: c.refreshCookie, Value: current.RefreshToken})
+		if csrfToken := c.csrf(); csrfToken != "" {
+			req.AddCookie(&http.Cookie{Name: c.csrfCookie, Value: csrfToken})
+			req.Header.Set(c.csrfHeader, csrfToken)
+		}
+
+		tokens, err := c.doTokenRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		c.SetTokens(tokens)
+
+		return tokens, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens.(*Tokens), nil
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (c *Client) doTokenRequest(req *http.Request) (*Tokens, error) {
+	if c.clientID != "" {
+		req.Header.Set(clientIDHeader, c.clientID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readAPIError(resp)
+	}
+	c.storeCSRF(resp)
+
+	var body tokenResponse
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode token response: %w", err)
+	}
+
+	// In cookie delivery mode the refresh token is only in the cookie.
+	if body.RefreshToken == "" {
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == c.refreshCookie {
+				body.RefreshToken = cookie.Value
+				break
+			}
+		}
+	}
+
+	expiresAt, err := tokenExpiry(body.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:     body.AccessToken,
+		RefreshToken:    body.RefreshToken,
+		AccessExpiresAt: expiresAt,
+	}, nil
+}
+
+func (c *Client) csrf() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.csrfToken
+}
+
+func (c *Client) storeCSRF(resp *http.Response) {
+	if c.csrfCookie == "" {
+		return
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == c.csrfCookie && cookie.Value != "" {
+			c.mu.Lock()
+			c.csrfToken = cookie.Value
+			c.mu.Unlock()
+			return
+		}
+	}
+}
+
+// tokenExpiry reads exp without verifying the signature: the client only
+// needs it to schedule refreshes, the token is verified by whoever accepts it.
+func tokenExpiry(token string) (time.Time, error) {
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("parse access token: %w", err)
+	}
+	if claims.ExpiresAt == nil {
+		return time.Time{}, errors.New("access token has no exp claim")
+	}
+	return claims.ExpiresAt.Time, nil
+}
+
+func readAPIError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	var body responser.Problem
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(data, &body) == nil {
+		apiErr.Code = body.Code
+		apiErr.Message = body.Detail
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return apiErr
+}
+
+func (c *Client) endpoint(path string, query url.Values) string {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
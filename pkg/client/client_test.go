@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"refresh/pkg/myerrors"
+	"refresh/pkg/responser"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAuthAPI struct {
+	accessTTL time.Duration
+	refreshes atomic.Int32
+	current   atomic.Value
+	// csrf enables double-submit protection of the refresh endpoint.
+	csrf string
+	// cookieOnly delivers the refresh token only in a cookie.
+	cookieOnly bool
+}
+
+func (f *fakeAuthAPI) issue(t *testing.T, w http.ResponseWriter) {
+	access, err := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
+		"jti": uuid.NewString(),
+		"exp": time.Now().Add(f.accessTTL).Unix(),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	refresh := uuid.NewString()
+	f.current.Store(refresh)
+	if f.csrf != "" {
+		http.SetCookie(w, &http.Cookie{Name: "csrf_token", Value: f.csrf})
+	}
+	if f.cookieOnly {
+		http.SetCookie(w, &http.Cookie{Name: defaultRefreshCookieName, Value: refresh})
+		refresh = ""
+	}
+	_ = json.NewEncoder(w).Encode(tokenResponse{AccessToken: access, RefreshToken: refresh})
+}
+
+func (f *fakeAuthAPI) server(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(loginPath, func(w http.ResponseWriter, r *http.Request) {
+		f.issue(t, w)
+	})
+	mux.HandleFunc(refreshPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if f.csrf != "" && r.Header.Get("X-CSRF-Token") != f.csrf {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		cookie, err := r.Cookie(defaultRefreshCookieName)
+		if err != nil || cookie.Value != f.current.Load() {
+			responser.SendError(w, myerrors.ErrInappropriateRefreshToken)
+			return
+		}
+		f.refreshes.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		f.issue(t, w)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient_AccessTokenRefreshesOnce(t *testing.T) {
+	api := &fakeAuthAPI{accessTTL: 10 * time.Second}
+	srv := api.server(t)
+
+	c, err := New(Config{BaseURL: srv.URL, RefreshBefore: time.Minute})
+	require.NoError(t, err)
+
+	_, err = c.Login(context.Background(), uuid.New())
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.AccessToken(context.Background())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), api.refreshes.Load())
+}
+
+func TestClient_Transport(t *testing.T) {
+	api := &fakeAuthAPI{accessTTL: time.Hour}
+	srv := api.server(t)
+
+	c, err := New(Config{BaseURL: srv.URL})
+	require.NoError(t, err)
+
+	tokens, err := c.Login(context.Background(), uuid.New())
+	require.NoError(t, err)
+
+	var calls atomic.Int32
+	resource := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first access token is rejected to force a refresh and retry.
+		if calls.Add(1) == 1 {
+			assert.Equal(t, "Bearer "+tokens.AccessToken, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.NotEqual(t, "Bearer "+tokens.AccessToken, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer resource.Close()
+
+	httpClient := &http.Client{Transport: c.Transport(nil)}
+	resp, err := httpClient.Get(resource.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(1), api.refreshes.Load())
+}
+
+func TestClient_NotAuthenticated(t *testing.T) {
+	c, err := New(Config{BaseURL: "http://localhost"})
+	require.NoError(t, err)
+
+	_, err = c.AccessToken(context.Background())
+	assert.ErrorIs(t, err, ErrNotAuthenticated)
+}
+
+func TestClient_RefreshReturnsAPIError(t *testing.T) {
+	api := &fakeAuthAPI{accessTTL: time.Minute}
+	srv := api.server(t)
+
+	c, err := New(Config{BaseURL: srv.URL})
+	require.NoError(t, err)
+
+	c.SetTokens(&Tokens{AccessToken: "access", RefreshToken: "unknown"})
+
+	_, err = c.Refresh(context.Background())
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.Equal(t, myerrors.CodeInappropriateRefreshToken, apiErr.Code)
+	assert.Equal(t, myerrors.ErrInappropriateRefreshToken.Error(), apiErr.Message)
+}
+
+func TestClient_RefreshSendsCSRFToken(t *testing.T) {
+	api := &fakeAuthAPI{accessTTL: time.Minute, csrf: "csrf-value"}
+	srv := api.server(t)
+
+	c, err := New(Config{BaseURL: srv.URL, CSRFCookieName: "csrf_token"})
+	require.NoError(t, err)
+
+	_, err = c.Login(context.Background(), uuid.New())
+	require.NoError(t, err)
+
+	_, err = c.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), api.refreshes.Load())
+}
+
+func TestClient_CookieOnlyDelivery(t *testing.T) {
+	api := &fakeAuthAPI{accessTTL: time.Minute, cookieOnly: true}
+	srv := api.server(t)
+
+	c, err := New(Config{BaseURL: srv.URL, ClientID: "web"})
+	require.NoError(t, err)
+
+	tokens, err := c.Login(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, api.current.Load(), tokens.RefreshToken)
+
+	_, err = c.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), api.refreshes.Load())
+}
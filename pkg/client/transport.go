@@ -0,0 +1,59 @@
+package client
+
+import (
+	"net/http"
+)
+
+type transport struct {
+	client *Client
+	base   http.RoundTripper
+}
+
+// Transport returns a RoundTripper that sets the bearer token on every
+// request. A 401 answer triggers one refresh and, when the request body can
+// be replayed, one retry.
+func (c *Client) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{client: c, base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.client.AccessToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(withBearer(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	tokens, err := t.client.refresh(req.Context(), token)
+	if err != nil {
+		return resp, nil
+	}
+
+	retry := withBearer(req, tokens.AccessToken)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	_ = resp.Body.Close()
+
+	return t.base.RoundTrip(retry)
+}
+
+func withBearer(req *http.Request, token string) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Authorization", "Bearer "+token)
+	return clone
+}
@@ -0,0 +1,254 @@
+// Package dpop verifies DPoP proofs (RFC 9449).
+//
+// A client proves possession of its key by signing a short-lived proof JWT
+// for every request and sending it in the DPoP header. Tokens issued against
+// a proof carry the key thumbprint in their cnf.jkt claim and are useless
+// without the private key. The auth service verifies proofs at its token
+// endpoints; resource servers verify them through pkg/authmw.
+//
+// Replayed proofs are detected with an in-memory cache, so each replica
+// keeps its own view; MaxAge bounds the window a proof could be replayed
+// against another replica.
+package dpop
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HeaderName is the request header carrying the proof.
+const HeaderName = "DPoP"
+
+// Scheme is the authorization scheme of DPoP-bound access tokens.
+const Scheme = "DPoP"
+
+const proofType = "dpop+jwt"
+
+const (
+	defaultMaxAge          = time.Minute
+	defaultLeeway          = 5 * time.Second
+	defaultReplayCacheSize = 100000
+)
+
+var (
+	ErrMissingProof = errors.New("dpop proof not found")
+	ErrInvalidProof = errors.New("invalid dpop proof")
+)
+
+// Algorithms are the accepted proof signing algorithms. Symmetric algorithms
+// are excluded because the verifier must not share the client's key.
+var Algorithms = []string{
+	"ES256", "ES384", "ES512",
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"EdDSA",
+}
+
+type Config struct {
+	// MaxAge is how long after its iat a proof is accepted.
+	MaxAge time.Duration `yaml:"maxAge" env-default:"1m"`
+	// Leeway tolerates client clocks running ahead of the server.
+	Leeway time.Duration `yaml:"leeway" env-default:"5s"`
+	// ReplayCacheSize bounds the number of remembered proof identifiers.
+	ReplayCacheSize int `yaml:"replayCacheSize" env-default:"100000"`
+	// BaseURL replaces the scheme and host the htu claim is compared with,
+	// for servers behind a TLS-terminating proxy. By default they are taken
+	// from the request.
+	BaseURL string `yaml:"baseURL"`
+}
+
+// Proof is a verified DPoP proof.
+type Proof struct {
+	ID string
+	// Thumbprint is the RFC 7638 SHA-256 thumbprint of the proof key, the
+	// cnf.jkt value of tokens bound to it.
+	Thumbprint string
+	IssuedAt   time.Time
+}
+
+type Verifier struct {
+	cfg     Config
+	baseURL *url.URL
+	seen    *replayCache
+	now     func() time.Time
+}
+
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = defaultMaxAge
+	}
+	if cfg.Leeway <= 0 {
+		cfg.Leeway = defaultLeeway
+	}
+	if cfg.ReplayCacheSize <= 0 {
+		cfg.ReplayCacheSize = defaultReplayCacheSize
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	v := &Verifier{
+		cfg:  cfg,
+		seen: newReplayCache(cfg.ReplayCacheSize),
+		now:  time.Now,
+	}
+	if cfg.BaseURL != "" {
+		v.baseURL, _ = url.Parse(cfg.BaseURL)
+	}
+
+	return v, nil
+}
+
+func (c Config) Validate() error {
+	if c.BaseURL == "" {
+		return nil
+	}
+	base, err := url.Parse(c.BaseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return fmt.Errorf("dpop: invalid base url %q", c.BaseURL)
+	}
+	return nil
+}
+
+// VerifyRequest verifies the proof sent with r. accessToken is the token the
+// request is authorized with, or empty at token endpoints; when set, the
+// proof must carry its hash in the ath claim.
+func (v *Verifier) VerifyRequest(r *http.Request, accessToken string) (*Proof, error) {
+	values := r.Header.Values(HeaderName)
+	switch len(values) {
+	case 0:
+		return nil, ErrMissingProof
+	case 1:
+	default:
+		return nil, fmt.Errorf("%w: multiple proofs", ErrInvalidProof)
+	}
+
+	return v.Verify(values[0], r.Method, v.requestURL(r), accessToken)
+}
+
+// Verify checks the proof signature and its htm, htu, iat, jti and ath
+// claims. A proof is accepted only once.
+func (v *Verifier) Verify(proof, method, uri, accessToken string) (*Proof, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods(Algorithms), jwt.WithoutClaimsValidation())
+
+	var (
+		c          proofClaims
+		thumbprint string
+	)
+	_, err := parser.ParseWithClaims(proof, &c, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); !strings.EqualFold(typ, proofType) {
+			return nil, fmt.Errorf("unexpected typ %q", typ)
+		}
+		jwk, err := parseJWK(token.Header["jwk"])
+		if err != nil {
+			return nil, fmt.Errorf("jwk header: %w", err)
+		}
+		if thumbprint, err = jwk.thumbprint(); err != nil {
+			return nil, err
+		}
+		return jwk.publicKey()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+
+	if c.ID == "" {
+		return nil, fmt.Errorf("%w: jti claim is missing", ErrInvalidProof)
+	}
+	if c.Method != method {
+		return nil, fmt.Errorf("%w: htm does not match the request", ErrInvalidProof)
+	}
+	if !sameURL(c.URI, uri) {
+		return nil, fmt.Errorf("%w: htu does not match the request", ErrInvalidProof)
+	}
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		if c.AccessTokenHash != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return nil, fmt.Errorf("%w: ath does not match the access token", ErrInvalidProof)
+		}
+	}
+
+	if c.IssuedAt == nil {
+		return nil, fmt.Errorf("%w: iat claim is missing", ErrInvalidProof)
+	}
+	now := v.now()
+	issuedAt := c.IssuedAt.Time
+	if issuedAt.After(now.Add(v.cfg.Leeway)) || now.After(issuedAt.Add(v.cfg.MaxAge)) {
+		return nil, fmt.Errorf("%w: iat is outside the acceptable window", ErrInvalidProof)
+	}
+
+	// A proof stays acceptable until iat+MaxAge, which is at most
+	// Leeway+MaxAge from now.
+	if !v.seen.add(thumbprint+"."+c.ID, now, now.Add(v.cfg.Leeway+v.cfg.MaxAge)) {
+		return nil, fmt.Errorf("%w: proof has already been used", ErrInvalidProof)
+	}
+
+	return &Proof{ID: c.ID, Thumbprint: thumbprint, IssuedAt: issuedAt}, nil
+}
+
+func (v *Verifier) requestURL(r *http.Request) string {
+	if v.baseURL != nil {
+		return strings.TrimSuffix(v.baseURL.String(), "/") + r.URL.Path
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+type proofClaims struct {
+	jwt.RegisteredClaims
+	Method          string `json:"htm"`
+	URI             string `json:"htu"`
+	AccessTokenHash string `json:"ath,omitempty"`
+}
+
+// sameURL compares htu with the request URI as RFC 9449 section 4.3 asks:
+// without query and fragment, after normalizing scheme, host and port.
+func sameURL(htu, uri string) bool {
+	a, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(hostPort(a), hostPort(b)) &&
+		a.EscapedPath() == b.EscapedPath()
+}
+
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" ||
+		(port == "443" && strings.EqualFold(u.Scheme, "https")) ||
+		(port == "80" && strings.EqualFold(u.Scheme, "http")) {
+		return u.Hostname()
+	}
+	return u.Host
+}
+
+type ctxKey struct{}
+
+func NewContext(ctx context.Context, p *Proof) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the verified proof of the request, if any.
+func FromContext(ctx context.Context) (*Proof, bool) {
+	p, ok := ctx.Value(ctxKey{}).(*Proof)
+	return p, ok
+}
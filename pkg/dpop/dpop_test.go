@@ -0,0 +1,190 @@
+package dpop
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testURL = "https://auth.example.com/api/v1/auth/refresh"
+
+func ecJWK(key *ecdsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func signProof(t *testing.T, key *ecdsa.PrivateKey, header map[string]interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = proofType
+	token.Header["jwk"] = ecJWK(key)
+	for k, v := range header {
+		token.Header[k] = v
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func proofClaimsFor(method, uri string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"jti": uuid.NewString(),
+		"htm": method,
+		"htu": uri,
+		"iat": time.Now().Unix(),
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	accessToken := "access-token"
+	sum := sha256.Sum256([]byte(accessToken))
+	ath := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	with := func(k string, v interface{}) jwt.MapClaims {
+		claims := proofClaimsFor(http.MethodPost, testURL)
+		claims[k] = v
+		return claims
+	}
+
+	tests := []struct {
+		name        string
+		header      map[string]interface{}
+		claims      jwt.MapClaims
+		accessToken string
+		expectedErr bool
+	}{
+		{
+			name:   "Success case",
+			claims: proofClaimsFor(http.MethodPost, testURL),
+		},
+		{
+			name:   "htu with query and default port",
+			claims: with("htu", "https://AUTH.example.com:443/api/v1/auth/refresh?x=1"),
+		},
+		{
+			name:        "Access token hash",
+			claims:      with("ath", ath),
+			accessToken: accessToken,
+		},
+		{
+			name:        "Wrong access token hash",
+			claims:      with("ath", "invalid"),
+			accessToken: accessToken,
+			expectedErr: true,
+		},
+		{
+			name:        "Wrong method",
+			claims:      with("htm", http.MethodGet),
+			expectedErr: true,
+		},
+		{
+			name:        "Wrong uri",
+			claims:      with("htu", "https://auth.example.com/api/v1/auth/login"),
+			expectedErr: true,
+		},
+		{
+			name:        "Stale iat",
+			claims:      with("iat", time.Now().Add(-2*time.Minute).Unix()),
+			expectedErr: true,
+		},
+		{
+			name:        "iat in the future",
+			claims:      with("iat", time.Now().Add(time.Minute).Unix()),
+			expectedErr: true,
+		},
+		{
+			name:        "Missing jti",
+			claims:      with("jti", ""),
+			expectedErr: true,
+		},
+		{
+			name:        "Wrong typ",
+			header:      map[string]interface{}{"typ": "JWT"},
+			claims:      proofClaimsFor(http.MethodPost, testURL),
+			expectedErr: true,
+		},
+		{
+			name:        "Private key in jwk",
+			header:      map[string]interface{}{"jwk": map[string]string{"kty": "EC", "crv": "P-256", "d": "secret"}},
+			claims:      proofClaimsFor(http.MethodPost, testURL),
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewVerifier(Config{})
+			require.NoError(t, err)
+
+			proof, err := v.Verify(signProof(t, key, tt.header, tt.claims), http.MethodPost, testURL, tt.accessToken)
+			if tt.expectedErr {
+				assert.ErrorIs(t, err, ErrInvalidProof)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, proof.Thumbprint, 43)
+		})
+	}
+}
+
+func TestVerifier_RejectsReplayAndSymmetricKeys(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	v, err := NewVerifier(Config{})
+	require.NoError(t, err)
+
+	proof := signProof(t, key, nil, proofClaimsFor(http.MethodPost, testURL))
+	_, err = v.Verify(proof, http.MethodPost, testURL, "")
+	require.NoError(t, err)
+	_, err = v.Verify(proof, http.MethodPost, testURL, "")
+	assert.ErrorIs(t, err, ErrInvalidProof)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, proofClaimsFor(http.MethodPost, testURL))
+	token.Header["typ"] = proofType
+	token.Header["jwk"] = map[string]string{"kty": "oct", "k": "c2VjcmV0"}
+	hs, err := token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = v.Verify(hs, http.MethodPost, testURL, "")
+	assert.ErrorIs(t, err, ErrInvalidProof)
+}
+
+func TestVerifier_VerifyRequest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	v, err := NewVerifier(Config{BaseURL: "https://auth.example.com"})
+	require.NoError(t, err)
+
+	// Behind a proxy the request itself arrives over plain HTTP.
+	req := httptest.NewRequest(http.MethodPost, "http://10.0.0.1:8080/api/v1/auth/refresh", nil)
+	_, err = v.VerifyRequest(req, "")
+	assert.ErrorIs(t, err, ErrMissingProof)
+
+	req.Header.Set(HeaderName, signProof(t, key, nil, proofClaimsFor(http.MethodPost, testURL)))
+	first, err := v.VerifyRequest(req, "")
+	require.NoError(t, err)
+
+	req.Header.Set(HeaderName, signProof(t, key, nil, proofClaimsFor(http.MethodPost, testURL)))
+	second, err := v.VerifyRequest(req, "")
+	require.NoError(t, err)
+	assert.Equal(t, first.Thumbprint, second.Thumbprint)
+}
@@ -0,0 +1,59 @@
+package dpop
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type replayEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// replayCache remembers proof identifiers until the proofs they belong to
+// could no longer be accepted. Entries are kept in insertion order, so
+// expired ones are always at the back. When the cache is full the oldest
+// entry is dropped even if it has not expired yet.
+type replayCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+func newReplayCache(size int) *replayCache {
+	return &replayCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// add records key and reports whether it was unseen.
+func (c *replayCache) add(key string, now, expiresAt time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for el := c.ll.Back(); el != nil; el = c.ll.Back() {
+		if now.Before(el.Value.(*replayEntry).expiresAt) {
+			break
+		}
+		c.removeElement(el)
+	}
+
+	if _, ok := c.items[key]; ok {
+		return false
+	}
+
+	c.items[key] = c.ll.PushFront(&replayEntry{key: key, expiresAt: expiresAt})
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+	return true
+}
+
+func (c *replayCache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*replayEntry).key)
+}
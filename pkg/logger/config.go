@@ -0,0 +1,15 @@
+package logger
+
+import "io"
+
+type Config struct {
+	// Format is "text" or "json".
+	Format string `yaml:"format" env:"LOG_FORMAT" env-default:"text"`
+	// Level is one of debug, info, warn or error.
+	Level string `yaml:"level" env:"LOG_LEVEL" env-default:"info"`
+}
+
+func (c Config) Validate() error {
+	_, err := New(io.Discard, c)
+	return err
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying log, typically a request-scoped
+// logger with correlation attributes.
+func NewContext(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the logger stored in ctx, or fallback if there is none.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if log, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return log
+	}
+	return fallback
+}
+
+type attrsKey struct{}
+
+type attrs struct {
+	mu   sync.Mutex
+	args []any
+}
+
+// WithAttrs returns a copy of ctx that collects the attributes added with
+// AddAttrs further down the call chain, and a function returning them.
+func WithAttrs(ctx context.Context) (context.Context, func() []any) {
+	a := &attrs{}
+	return context.WithValue(ctx, attrsKey{}, a), func() []any {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.args
+	}
+}
+
+// AddAttrs records attributes only known deep in the call chain, such as
+// the user a request turned out to be made by, for the log line that sums
+// up the operation. It is a no-op when ctx does not collect attributes.
+func AddAttrs(ctx context.Context, args ...any) {
+	if a, ok := ctx.Value(attrsKey{}).(*attrs); ok {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.args = append(a.args, args...)
+	}
+}
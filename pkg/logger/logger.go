@@ -1,13 +1,73 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 )
 
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// SetupLogger returns a debug-level text logger, as used by tests and tools
+// that run without a config.
 func SetupLogger() *slog.Logger {
-	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
-	}))
+	log, _ := New(os.Stdout, Config{Format: FormatText, Level: "debug"})
 	return log
 }
+
+// NewFromConfig returns the service logger writing to stdout, together with
+// its level so that the level can be changed while the service runs.
+func NewFromConfig(cfg Config) (*slog.Logger, *slog.LevelVar, error) {
+	level := new(slog.LevelVar)
+	if err := SetLevel(level, cfg.Level); err != nil {
+		return nil, nil, err
+	}
+
+	log, err := newLogger(os.Stdout, cfg.Format, level)
+	if err != nil {
+		return nil, nil, err
+	}
+	return log, level, nil
+}
+
+// New builds a logger writing to w. Every record passes through
+// RedactingHandler, so secrets are masked regardless of the call site.
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	level := new(slog.LevelVar)
+	if err := SetLevel(level, cfg.Level); err != nil {
+		return nil, err
+	}
+	return newLogger(w, cfg.Format, level)
+}
+
+// SetLevel sets level to the named one: debug, info, warn or error. An empty
+// name leaves level unchanged.
+func SetLevel(level *slog.LevelVar, name string) error {
+	if name == "" {
+		return nil
+	}
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("log level: %w", err)
+	}
+	return nil
+}
+
+func newLogger(w io.Writer, format string, level slog.Leveler) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch format {
+	case FormatText, "":
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	return slog.New(NewRedactingHandler(handler)), nil
+}
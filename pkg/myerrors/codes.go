@@ -0,0 +1,53 @@
+package myerrors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Stable machine-readable codes. Clients match on these instead of messages,
+// so existing values must never change.
+const (
+	CodeInvalidToken              = "invalid_token"
+	CodeTokenExpired              = "token_expired"
+	CodeInappropriateRefreshToken = "inappropriate_refresh_token"
+	CodeTokenRevoked              = "token_revoked"
+	CodeInvalidScope              = "invalid_scope"
+	CodeTokenBindingMismatch      = "token_binding_mismatch"
+	CodeInvalidDPoPProof          = "invalid_dpop_proof"
+	CodeMissingToken              = "missing_token"
+	CodeInsufficientScope         = "insufficient_scope"
+	CodeCSRF                      = "csrf_failed"
+
+	CodeBadRequest   = "bad_request"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeInternal     = "internal_error"
+)
+
+type definition struct {
+	err    error
+	code   string
+	status int
+}
+
+var definitions = []definition{
+	{ErrInvalidToken, CodeInvalidToken, http.StatusUnauthorized},
+	{ErrTokenExpired, CodeTokenExpired, http.StatusUnauthorized},
+	{ErrInappropriateRefreshToken, CodeInappropriateRefreshToken, http.StatusUnauthorized},
+	{ErrTokenRevoked, CodeTokenRevoked, http.StatusUnauthorized},
+	{ErrInvalidScope, CodeInvalidScope, http.StatusBadRequest},
+	{ErrTokenBindingMismatch, CodeTokenBindingMismatch, http.StatusUnauthorized},
+	{ErrInvalidDPoPProof, CodeInvalidDPoPProof, http.StatusBadRequest},
+}
+
+// Lookup returns the code and HTTP status for err. ok is false for errors
+// that are not declared in this package; they map to CodeInternal and 500.
+func Lookup(err error) (code string, status int, ok bool) {
+	for _, d := range definitions {
+		if errors.Is(err, d.err) {
+			return d.code, d.status, true
+		}
+	}
+	return CodeInternal, http.StatusInternalServerError, false
+}
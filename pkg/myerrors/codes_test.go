@@ -0,0 +1,41 @@
+package myerrors
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   string
+		wantStatus int
+		wantOK     bool
+	}{
+		{"invalid token", ErrInvalidToken, CodeInvalidToken, http.StatusUnauthorized, true},
+		{"wrapped", fmt.Errorf("validate: %w", ErrTokenExpired), CodeTokenExpired, http.StatusUnauthorized, true},
+		{"invalid scope", ErrInvalidScope, CodeInvalidScope, http.StatusBadRequest, true},
+		{"unknown", errors.New("boom"), CodeInternal, http.StatusInternalServerError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, status, ok := Lookup(tt.err)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+}
+
+func TestDefinitionsHaveUniqueCodes(t *testing.T) {
+	codes := make(map[string]bool)
+	for _, d := range definitions {
+		assert.False(t, codes[d.code], "duplicate code %s", d.code)
+		codes[d.code] = true
+	}
+}
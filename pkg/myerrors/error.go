@@ -6,4 +6,8 @@ var (
 	ErrInvalidToken              = errors.New("invalid token")
 	ErrTokenExpired              = errors.New("token expired")
 	ErrInappropriateRefreshToken = errors.New("inappropriate refresh token")
+	ErrTokenRevoked              = errors.New("token revoked")
+	ErrInvalidScope              = errors.New("invalid scope")
+	ErrTokenBindingMismatch      = errors.New("token is bound to a different certificate or key")
+	ErrInvalidDPoPProof          = errors.New("invalid dpop proof")
 )
@@ -3,10 +3,31 @@ package responser
 import (
 	"encoding/json"
 	"net/http"
+	"refresh/pkg/myerrors"
 )
 
-type MessageResponse struct {
-	Msg string `json:"msg"`
+const (
+	ProblemContentType = "application/problem+json"
+
+	// RequestIDHeader is copied into problem bodies when it is set on the
+	// response before the error is written.
+	RequestIDHeader = "X-Request-ID"
+)
+
+// Problem is an RFC 7807 problem details object. Code is a stable,
+// machine-readable identifier from pkg/myerrors.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// OAuthErrorResponse is the error body defined by RFC 6749 section 5.2.
+type OAuthErrorResponse struct {
+	Error string `json:"error"`
 }
 
 func Send200(w http.ResponseWriter, v interface{}) {
@@ -20,28 +41,66 @@ func Send200(w http.ResponseWriter, v interface{}) {
 }
 
 func Send400(w http.ResponseWriter, msg string) {
-	resp, err := json.Marshal(MessageResponse{msg})
+	SendProblem(w, http.StatusBadRequest, myerrors.CodeBadRequest, msg)
+}
+
+func Send401(w http.ResponseWriter, msg string) {
+	SendProblem(w, http.StatusUnauthorized, myerrors.CodeUnauthorized, msg)
+}
+
+func Send403(w http.ResponseWriter, msg string) {
+	SendProblem(w, http.StatusForbidden, myerrors.CodeForbidden, msg)
+}
+
+func Send500(w http.ResponseWriter) {
+	SendProblem(w, http.StatusInternalServerError, myerrors.CodeInternal, "internal server error")
+}
+
+// SendError writes err with the status and code registered in pkg/myerrors.
+// Errors that are not registered there are answered with 500 and a generic
+// detail so implementation details do not leak.
+func SendError(w http.ResponseWriter, err error) {
+	code, status, ok := myerrors.Lookup(err)
+	if !ok {
+		Send500(w)
+		return
+	}
+	SendProblem(w, status, code, err.Error())
+}
+
+func SendProblem(w http.ResponseWriter, status int, code, detail string) {
+	resp, err := json.Marshal(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Code:      code,
+		RequestID: w.Header().Get(RequestIDHeader),
+	})
 	if err != nil {
 		return
 	}
-	w.WriteHeader(http.StatusBadRequest)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(status)
 	_, _ = w.Write(resp)
 }
 
-func Send401(w http.ResponseWriter, msg string) {
-	resp, err := json.Marshal(MessageResponse{msg})
+func SendOAuthError(w http.ResponseWriter, status int, code string) {
+	resp, err := json.Marshal(OAuthErrorResponse{code})
 	if err != nil {
 		return
 	}
-	w.WriteHeader(http.StatusUnauthorized)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	_, _ = w.Write(resp)
 }
 
-func Send500(w http.ResponseWriter) {
-	resp, err := json.Marshal(MessageResponse{"internal server error"})
+func Send503(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	resp, err := json.Marshal(v)
 	if err != nil {
 		return
 	}
-	w.WriteHeader(http.StatusInternalServerError)
 	_, _ = w.Write(resp)
 }
@@ -0,0 +1,67 @@
+package responser
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"refresh/pkg/myerrors"
+	"testing"
+)
+
+func TestSendError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		requestID  string
+		wantStatus int
+		want       Problem
+	}{
+		{
+			name:       "registered error",
+			err:        myerrors.ErrTokenRevoked,
+			wantStatus: http.StatusUnauthorized,
+			want: Problem{
+				Type:   "about:blank",
+				Title:  "Unauthorized",
+				Status: http.StatusUnauthorized,
+				Detail: "token revoked",
+				Code:   myerrors.CodeTokenRevoked,
+			},
+		},
+		{
+			name:       "unknown error does not leak",
+			err:        errors.New("pq: connection refused"),
+			requestID:  "req-1",
+			wantStatus: http.StatusInternalServerError,
+			want: Problem{
+				Type:      "about:blank",
+				Title:     "Internal Server Error",
+				Status:    http.StatusInternalServerError,
+				Detail:    "internal server error",
+				Code:      myerrors.CodeInternal,
+				RequestID: "req-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if tt.requestID != "" {
+				w.Header().Set(RequestIDHeader, tt.requestID)
+			}
+
+			SendError(w, tt.err)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+			var got Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}